@@ -1,14 +1,19 @@
 package main
 
 import (
+	"context"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"log/slog"
 	"os"
+	"time"
 
+	"aka-server/internal/aka"
 	"aka-server/internal/api"
 	"aka-server/internal/config"
 	"aka-server/internal/db"
+	"aka-server/internal/eapid"
 	"aka-server/internal/logger"
 	"aka-server/internal/service"
 
@@ -20,8 +25,22 @@ func main() {
 	install := flag.Bool("install", false, "Install as systemd service")
 	uninstall := flag.Bool("uninstall", false, "Uninstall systemd service")
 	serviceName := flag.String("service-name", "aka-server", "Name of the systemd service")
+	selfTest := flag.Bool("selftest", false, "Run the TS 35.208 conformance tests and exit")
 	flag.Parse()
 
+	if *selfTest {
+		for i := range aka.TS35208TestSets {
+			ts := &aka.TS35208TestSets[i]
+			if err := ts.Run(); err != nil {
+				fmt.Printf("FAIL %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("PASS %s\n", ts.Name)
+		}
+		fmt.Println("Self-test passed.")
+		return
+	}
+
 	if *install {
 		if err := service.Install(*serviceName, "AKA API Server"); err != nil {
 			fmt.Printf("Failed to install service: %v\n", err)
@@ -51,18 +70,96 @@ func main() {
 	logger.InitLogger(cfg.LogFile, cfg.LogMaxSize, cfg.LogMaxBackups, cfg.LogMaxAge)
 	slog.Info("Starting AKA Server...")
 
+	// Known-answer self-test: refuse to start with a broken algorithm.
+	if err := aka.SelfTest(); err != nil {
+		slog.Error("Self-test failed", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("Self-test passed")
+
 	// Initialize Database
-	dbURL := fmt.Sprintf("postgres://%s:%s@%s:%s/%s", cfg.DBUser, cfg.DBPassword, cfg.DBHost, cfg.DBPort, cfg.DBName)
-	repo, err := db.NewRepository(dbURL)
+	var repo db.Store
+	switch cfg.DBBackend {
+	case "postgres":
+		dbURL := fmt.Sprintf("postgres://%s:%s@%s:%s/%s", cfg.DBUser, cfg.DBPassword, cfg.DBHost, cfg.DBPort, cfg.DBName)
+		repo, err = db.NewRepository(dbURL)
+	case "sqlite":
+		repo, err = db.NewSQLiteStore(cfg.DBPath)
+	case "memory":
+		slog.Warn("DB_BACKEND is memory: subscribers are lost on restart")
+		repo = db.NewMemoryStore()
+	default:
+		err = fmt.Errorf("unknown DB_BACKEND %q", cfg.DBBackend)
+	}
 	if err != nil {
 		slog.Error("Failed to connect to database", "error", err)
 		os.Exit(1)
 	}
 	defer repo.Close()
-	slog.Info("Connected to database")
+	slog.Info("Connected to database", "backend", cfg.DBBackend)
+
+	// Initialize Vector Generator
+	for name, v := range map[string]int{
+		"SQN_DELTA":            cfg.SQNDelta,
+		"SQN_RESYNC_MAX_AHEAD": cfg.SQNResyncMaxAhead,
+		"SQN_WRAP_MARGIN":      cfg.SQNWrapMargin,
+	} {
+		if v < 0 {
+			slog.Error("Invalid SQN configuration", "error", fmt.Sprintf("%s must not be negative: %d", name, v))
+			os.Exit(1)
+		}
+	}
+	gen, err := aka.NewGenerator(aka.SQNConfig{
+		INDBits:         uint(cfg.SQNINDBits),
+		INDAllocation:   cfg.SQNINDAllocation,
+		Delta:           uint64(cfg.SQNDelta),
+		ResyncMaxAhead:  uint64(cfg.SQNResyncMaxAhead),
+		WrapMargin:      uint64(cfg.SQNWrapMargin),
+		TimeGranularity: cfg.SQNTimeGranularity,
+	})
+	if err != nil {
+		slog.Error("Invalid SQN configuration", "error", err)
+		os.Exit(1)
+	}
+	if cfg.DebugRandSeed != "" {
+		slog.Warn("DEBUG_RAND_SEED is set: RAND is predictable, do not use in production")
+		gen.Rand = aka.NewDeterministicRandSource([]byte(cfg.DebugRandSeed))
+	}
+
+	for _, plmn := range cfg.HomePLMNs {
+		if len(plmn) < 5 {
+			slog.Error("Invalid HOME_PLMNS entry", "plmn", plmn)
+			os.Exit(1)
+		}
+		if _, err := aka.PLMNID(plmn[:3], plmn[3:]); err != nil {
+			slog.Error("Invalid HOME_PLMNS entry", "plmn", plmn, "error", err)
+			os.Exit(1)
+		}
+	}
+
+	if cfg.AuthEventRetention != 0 {
+		if cfg.AuthEventRetention < cfg.AuthResyncWindow {
+			slog.Error("AUTH_EVENT_RETENTION must not be shorter than AUTH_RESYNC_WINDOW",
+				"retention", cfg.AuthEventRetention, "window", cfg.AuthResyncWindow)
+			os.Exit(1)
+		}
+		go pruneAuthEvents(repo, cfg.AuthEventRetention)
+	}
 
 	// Initialize API Handler
-	handler := api.NewHandler(repo, cfg)
+	handler := api.NewHandler(repo, gen, cfg)
+	if cfg.EAPIdentityKey == "" {
+		slog.Warn("EAP_IDENTITY_KEY is not set: EAP pseudonyms will not survive a restart")
+	} else {
+		key, err := hex.DecodeString(cfg.EAPIdentityKey)
+		if err == nil {
+			handler.Identities, err = eapid.NewCodec(key)
+		}
+		if err != nil {
+			slog.Error("Invalid EAP_IDENTITY_KEY", "error", err)
+			os.Exit(1)
+		}
+	}
 
 	// Setup Router
 	gin.SetMode(gin.ReleaseMode)
@@ -90,3 +187,17 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// pruneAuthEvents deletes auth events older than retention once an hour, so
+// that the table only holds what resync binding and auditing still need.
+func pruneAuthEvents(repo db.Store, retention time.Duration) {
+	for {
+		n, err := repo.DeleteAuthEventsBefore(context.Background(), time.Now().Add(-retention))
+		if err != nil {
+			slog.Error("Failed to prune auth events", "error", err)
+		} else if n > 0 {
+			slog.Info("Pruned auth events", "count", n, "retention", retention)
+		}
+		time.Sleep(time.Hour)
+	}
+}
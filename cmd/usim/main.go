@@ -0,0 +1,184 @@
+// Command usim drives POST /api/v1/auth/:imsi against a running server with
+// a simulated USIM, including resynchronization when the SQNs differ.
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"aka-server/internal/aka"
+	"aka-server/internal/model"
+	"aka-server/internal/usim"
+)
+
+func main() {
+	server := flag.String("server", "http://localhost:8080", "Base URL of the AKA server")
+	imsi := flag.String("imsi", "", "IMSI of the subscriber (required)")
+	ki := flag.String("ki", "", "Ki in hex (required)")
+	opc := flag.String("opc", "", "OPc in hex (TOPc for TUAK)")
+	op := flag.String("op", "", "OP in hex, used to derive OPc when -opc is not given")
+	algorithm := flag.String("algorithm", aka.AlgorithmMilenage, "milenage or tuak")
+	sqn := flag.String("sqn", "000000000000", "Initial USIM SQN_MS in hex; set it ahead of the server to force a resync")
+	indBits := flag.Uint("ind-bits", 5, "IND length in bits, as SQN_IND_BITS on the server")
+	delta := flag.Uint64("delta", 1<<28, "Largest accepted SEQ step, as SQN_DELTA on the server")
+	statePath := flag.String("state", "", "File to load and save the USIM SQN state")
+	rounds := flag.Int("rounds", 1, "Number of authentications to run")
+	flag.Parse()
+
+	if *imsi == "" || *ki == "" || (*opc == "" && *op == "") {
+		fmt.Println("-imsi, -ki and -opc or -op are required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *opc == "" {
+		var err error
+		if *opc, err = aka.ComputeOPc(*algorithm, *ki, *op); err != nil {
+			fmt.Printf("Failed to derive OPc: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	u, err := usim.New(&model.Subscriber{Ki: *ki, Opc: *opc, SQN: *sqn, Algorithm: *algorithm}, *indBits, *delta)
+	if err != nil {
+		fmt.Printf("Failed to create USIM: %v\n", err)
+		os.Exit(1)
+	}
+	if *statePath != "" {
+		if err := loadState(u, *statePath); err != nil {
+			fmt.Printf("Failed to load state: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	c := &client{url: strings.TrimRight(*server, "/") + "/api/v1/auth/" + *imsi}
+	failed := false
+	for i := 1; i <= *rounds; i++ {
+		if err := authenticate(c, u); err != nil {
+			fmt.Printf("Round %d: FAIL: %v\n", i, err)
+			failed = true
+			break
+		}
+		fmt.Printf("Round %d: OK\n", i)
+	}
+
+	if *statePath != "" {
+		if err := saveState(u, *statePath); err != nil {
+			fmt.Printf("Failed to save state: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// authenticate runs one authentication, resynchronizing once if the USIM
+// reports a sync failure.
+func authenticate(c *client, u *usim.USIM) error {
+	vec, err := c.request(nil)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("  RAND=%s AUTN=%s\n", vec.Rand, vec.Autn)
+
+	res, err := check(u, vec)
+	var sf *usim.SyncFailure
+	if errors.As(err, &sf) {
+		auts := hex.EncodeToString(sf.AUTS)
+		fmt.Printf("  Sync failure, resynchronizing with AUTS=%s\n", auts)
+		if vec, err = c.request(map[string]string{"rand": vec.Rand, "auts": auts}); err != nil {
+			return err
+		}
+		fmt.Printf("  RAND=%s AUTN=%s\n", vec.Rand, vec.Autn)
+		res, err = check(u, vec)
+	}
+	if err != nil {
+		return err
+	}
+
+	xres, err := hex.DecodeString(vec.Xres)
+	if err != nil {
+		return fmt.Errorf("invalid XRES: %w", err)
+	}
+	if subtle.ConstantTimeCompare(res, xres) != 1 {
+		return fmt.Errorf("RES %x does not match XRES %s", res, vec.Xres)
+	}
+	fmt.Printf("  RES=%x matches XRES\n", res)
+	return nil
+}
+
+func check(u *usim.USIM, vec *aka.AuthVector) ([]byte, error) {
+	rand, err := hex.DecodeString(vec.Rand)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RAND: %w", err)
+	}
+	autn, err := hex.DecodeString(vec.Autn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AUTN: %w", err)
+	}
+	res, _, _, err := u.Authenticate(rand, autn)
+	return res, err
+}
+
+type client struct {
+	url string
+}
+
+func (c *client) request(body any) (*aka.AuthVector, error) {
+	if body == nil {
+		body = struct{}{}
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.Post(c.url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		return nil, fmt.Errorf("server returned %s: %s", resp.Status, e.Error)
+	}
+	var vec aka.AuthVector
+	if err := json.NewDecoder(resp.Body).Decode(&vec); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	return &vec, nil
+}
+
+func loadState(u *usim.USIM, path string) error {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var s usim.State
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return u.Restore(s)
+}
+
+func saveState(u *usim.USIM, path string) error {
+	b, err := json.MarshalIndent(u.State, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0600)
+}
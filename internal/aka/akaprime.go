@@ -0,0 +1,31 @@
+package aka
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// KDF FC value for CK'/IK' derivation (TS 33.402 Annex A.2).
+const fcCKIKPrime = 0x20
+
+// DeriveAKAPrime returns a copy of vec with CK and IK replaced by CK' and IK'
+// for EAP-AKA' (RFC 9048 3.3, TS 33.402 Annex A.2), bound to the access
+// network name netName. The quintet must have been generated with the AMF
+// separation bit set.
+func DeriveAKAPrime(vec *AuthVector, netName string) (*AuthVector, error) {
+	if netName == "" {
+		return nil, fmt.Errorf("access network name is required")
+	}
+	q, err := decodeQuintet(vec)
+	if err != nil {
+		return nil, err
+	}
+
+	key := append(append([]byte{}, q.ck...), q.ik...)
+	out := kdf(key, fcCKIKPrime, []byte(netName), q.autn[:6])
+
+	prime := *vec
+	prime.Ck = hex.EncodeToString(out[:16])
+	prime.Ik = hex.EncodeToString(out[16:])
+	return &prime, nil
+}
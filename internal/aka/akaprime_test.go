@@ -0,0 +1,37 @@
+package aka
+
+import "testing"
+
+func TestDeriveAKAPrime(t *testing.T) {
+	// RFC 5448 Appendix C, test case 1.
+	vec := &AuthVector{
+		Rand: "81e92b6c0ee0e12ebceba8d92a99dfa5",
+		Autn: "bb52e91c747ac3ab2a5c23d15ee351d5",
+		Xres: "28d7b0f2a2ec3de5",
+		Ck:   "5349fbe098649f948f5d2e973a81c00f",
+		Ik:   "9744871ad32bf9bbd1dd5ce54e3e2e5a",
+	}
+
+	got, err := DeriveAKAPrime(vec, "WLAN")
+	if err != nil {
+		t.Fatalf("DeriveAKAPrime failed: %v", err)
+	}
+	if got.Ck != "0093962d0dd84aa5684b045c9edffa04" {
+		t.Errorf("Unexpected CK' %s", got.Ck)
+	}
+	if got.Ik != "ccfc230ca74fcc96c0a5d61164f5a76c" {
+		t.Errorf("Unexpected IK' %s", got.Ik)
+	}
+	if got.Rand != vec.Rand || got.Autn != vec.Autn || got.Xres != vec.Xres {
+		t.Errorf("RAND/AUTN/XRES must be passed through unchanged")
+	}
+	if vec.Ck != "5349fbe098649f948f5d2e973a81c00f" {
+		t.Errorf("Input vector modified")
+	}
+}
+
+func TestDeriveAKAPrimeRequiresNetworkName(t *testing.T) {
+	if _, err := DeriveAKAPrime(testSet1Vector, ""); err == nil {
+		t.Error("Expected error for empty access network name")
+	}
+}
@@ -0,0 +1,72 @@
+package aka
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// KDF FC values from TS 33.535 Annex A.
+const (
+	fcKAKMA = 0x80
+	fcATID  = 0x81
+	fcKAF   = 0x82
+)
+
+// uaProtocolIDLen is the length of a Ua security protocol identifier
+// (TS 33.220 Annex H).
+const uaProtocolIDLen = 5
+
+// DeriveAKMA derives KAKMA (TS 33.535 Annex A.2) and A-TID (A.3) for supi
+// from KAUSF. supi is the IMSI digits.
+func DeriveAKMA(kausfHex, supi string) (kakma, atid string, err error) {
+	kausf, err := hex.DecodeString(kausfHex)
+	if err != nil || len(kausf) != 32 {
+		return "", "", fmt.Errorf("invalid KAUSF")
+	}
+	if supi == "" {
+		return "", "", fmt.Errorf("SUPI is required")
+	}
+	kakma = hex.EncodeToString(kdf(kausf, fcKAKMA, []byte("AKMA"), []byte(supi)))
+	atid = hex.EncodeToString(kdf(kausf, fcATID, []byte("A-TID"), []byte(supi)))
+	return kakma, atid, nil
+}
+
+// AKMAKeyID builds the A-KID NAI (TS 33.535 6.1): the username carries the
+// routing indicator and A-TID, the realm the home network identifier.
+func AKMAKeyID(atid, routingIndicator, mcc, mnc string) (string, error) {
+	if len(mnc) == 2 {
+		mnc = "0" + mnc
+	}
+	if !IsDigits(mcc, 3, 3) || !IsDigits(mnc, 3, 3) {
+		return "", fmt.Errorf("invalid MCC/MNC: %s/%s", mcc, mnc)
+	}
+	if routingIndicator == "" {
+		routingIndicator = "0"
+	}
+	return fmt.Sprintf("%s.%s@5gc.mnc%s.mcc%s.3gppnetwork.org", routingIndicator, atid, mnc, mcc), nil
+}
+
+// ApplicationID builds an AF_ID or NAF_ID: the FQDN followed by the Ua
+// security protocol identifier (TS 33.220 Annex H). An empty uaProtocolID
+// leaves the FQDN alone.
+func ApplicationID(fqdn, uaProtocolID string) ([]byte, error) {
+	if fqdn == "" || strings.ContainsAny(fqdn, " /@") {
+		return nil, fmt.Errorf("invalid FQDN: %q", fqdn)
+	}
+	ua, err := hex.DecodeString(uaProtocolID)
+	if err != nil || (len(ua) != 0 && len(ua) != uaProtocolIDLen) {
+		return nil, fmt.Errorf("invalid Ua security protocol identifier: %q", uaProtocolID)
+	}
+	return append([]byte(fqdn), ua...), nil
+}
+
+// DeriveKAF derives the application function key KAF from KAKMA
+// (TS 33.535 Annex A.4).
+func DeriveKAF(kakmaHex string, afID []byte) (string, error) {
+	kakma, err := hex.DecodeString(kakmaHex)
+	if err != nil || len(kakma) != 32 {
+		return "", fmt.Errorf("invalid KAKMA")
+	}
+	return hex.EncodeToString(kdf(kakma, fcKAF, afID)), nil
+}
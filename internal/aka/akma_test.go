@@ -0,0 +1,90 @@
+package aka
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+// The KAUSF below is arbitrary, not a published test vector, and the
+// expected KAKMA, A-TID and KAF were produced by this code; they only guard
+// against regressions. TestAKMAKDFInput checks the KDF input encoding
+// against Annex A.
+func TestDeriveAKMA(t *testing.T) {
+	const kausf = "b33c6dc1b2bca8fd4ddb7baa5e8a3e1de4f8c6e2eeefa3b6e6b1b1c5cfa1b4d2"
+	kakma, atid, err := DeriveAKMA(kausf, "001010123456789")
+	if err != nil {
+		t.Fatalf("DeriveAKMA failed: %v", err)
+	}
+	if kakma != "af35e273d9ceb18685403ee6dbe3b9dea5c9c09418c86783d18f580fcae81e0e" {
+		t.Errorf("Unexpected KAKMA %s", kakma)
+	}
+	if atid != "11c81ba9defcf6ec6cb6b9bb170b732a64ae1b83f8d1f2a60a99ce2e0fe4d489" {
+		t.Errorf("Unexpected A-TID %s", atid)
+	}
+
+	akid, err := AKMAKeyID(atid, "", "001", "01")
+	if err != nil {
+		t.Fatalf("AKMAKeyID failed: %v", err)
+	}
+	if akid != "0."+atid+"@5gc.mnc001.mcc001.3gppnetwork.org" {
+		t.Errorf("Unexpected A-KID %s", akid)
+	}
+
+	afID, err := ApplicationID("app.example.com", "0100000102")
+	if err != nil {
+		t.Fatalf("ApplicationID failed: %v", err)
+	}
+	kaf, err := DeriveKAF(kakma, afID)
+	if err != nil {
+		t.Fatalf("DeriveKAF failed: %v", err)
+	}
+	if kaf != "2629302749ae6e2ca6f6eb41d0ff89a1f0430c132651390f93a1f351ba4500f3" {
+		t.Errorf("Unexpected KAF %s", kaf)
+	}
+
+	if _, err := ApplicationID("app.example.com", "01"); err == nil {
+		t.Error("Expected error for a short Ua security protocol identifier")
+	}
+	if _, _, err := DeriveAKMA("00", "001010123456789"); err == nil {
+		t.Error("Expected error for a short KAUSF")
+	}
+}
+
+// TestAKMAKDFInput spells out the S strings of TS 33.535 Annex A.2 (KAKMA:
+// FC=0x80, P0="AKMA", P1=SUPI), A.3 (A-TID: FC=0x81, P0="A-TID", P1=SUPI)
+// and A.4 (KAF: FC=0x82, P0=AF_ID) byte by byte.
+func TestAKMAKDFInput(t *testing.T) {
+	const kausf = "b33c6dc1b2bca8fd4ddb7baa5e8a3e1de4f8c6e2eeefa3b6e6b1b1c5cfa1b4d2"
+	const supi = "303031303130313233343536373839" + "000f" // "001010123456789", L=15
+	hmacHex := func(key, s string) string {
+		mac := hmac.New(sha256.New, mustHex(t, key))
+		mac.Write(mustHex(t, s))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	kakma, atid, err := DeriveAKMA(kausf, "001010123456789")
+	if err != nil {
+		t.Fatalf("DeriveAKMA failed: %v", err)
+	}
+	if want := hmacHex(kausf, "80"+"414b4d41"+"0004"+supi); kakma != want {
+		t.Errorf("KAKMA: got %s, want %s", kakma, want)
+	}
+	if want := hmacHex(kausf, "81"+"412d544944"+"0005"+supi); atid != want {
+		t.Errorf("A-TID: got %s, want %s", atid, want)
+	}
+
+	afID, err := ApplicationID("app.example.com", "0100000102")
+	if err != nil {
+		t.Fatalf("ApplicationID failed: %v", err)
+	}
+	kaf, err := DeriveKAF(kakma, afID)
+	if err != nil {
+		t.Fatalf("DeriveKAF failed: %v", err)
+	}
+	// AF_ID = "app.example.com" || Ua security protocol identifier, L=20.
+	if want := hmacHex(kakma, "82"+"6170702e6578616d706c652e636f6d"+"0100000102"+"0014"); kaf != want {
+		t.Errorf("KAF: got %s, want %s", kaf, want)
+	}
+}
@@ -0,0 +1,130 @@
+package aka
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+
+	"aka-server/internal/model"
+
+	"github.com/wmnsk/milenage"
+)
+
+// Algorithm names accepted in model.Subscriber.Algorithm. An empty value
+// means AlgorithmMilenage.
+const (
+	AlgorithmMilenage = "milenage"
+	AlgorithmTUAK     = "tuak"
+)
+
+// AlgorithmSet is the f1, f1*, f2-f5 and f5* function set of an AKA
+// algorithm, keyed for one subscriber.
+type AlgorithmSet interface {
+	F1(rand, sqn, amf []byte) ([]byte, error)
+	F1Star(rand, sqn, amf []byte) ([]byte, error)
+	F2345(rand []byte) (res, ck, ik, ak []byte, err error)
+	F5Star(rand []byte) ([]byte, error)
+}
+
+// ValidateAlgorithm checks that name is a supported algorithm.
+func ValidateAlgorithm(name string) error {
+	switch name {
+	case "", AlgorithmMilenage, AlgorithmTUAK:
+		return nil
+	}
+	return fmt.Errorf("unsupported algorithm: %q", name)
+}
+
+// ValidateKeyLengths checks the hex encoded Ki and OPc against algorithm:
+// Milenage takes a 16-byte K and OPc, TUAK a 16- or 32-byte K and a 32-byte
+// TOPc. An empty opc is not checked.
+func ValidateKeyLengths(algorithm, kiHex, opcHex string) error {
+	ki, err := hex.DecodeString(kiHex)
+	if err != nil {
+		return fmt.Errorf("invalid Ki: %w", err)
+	}
+	opc, err := hex.DecodeString(opcHex)
+	if err != nil {
+		return fmt.Errorf("invalid OPC: %w", err)
+	}
+	return checkKeyLengths(algorithm, ki, opc)
+}
+
+func checkKeyLengths(algorithm string, ki, opc []byte) error {
+	if algorithm == AlgorithmTUAK {
+		if err := checkTuakKey(ki); err != nil {
+			return err
+		}
+		if len(opc) != 0 && len(opc) != 32 {
+			return fmt.Errorf("invalid TOPc length for tuak: %d", len(opc))
+		}
+		return nil
+	}
+	if len(ki) != 16 {
+		return fmt.Errorf("invalid Ki length for milenage: %d", len(ki))
+	}
+	if len(opc) != 0 && len(opc) != 16 {
+		return fmt.Errorf("invalid OPC length for milenage: %d", len(opc))
+	}
+	return nil
+}
+
+// NewAlgorithmSet returns the algorithm set selected by sub.Algorithm. For
+// TUAK the subscriber's Opc field holds TOPc. Milenage honours the c/r
+// constants of sub.Operator, if any, and an empty Opc is derived from the
+// operator's OP.
+func NewAlgorithmSet(sub *model.Subscriber) (AlgorithmSet, error) {
+	if err := ValidateAlgorithm(sub.Algorithm); err != nil {
+		return nil, err
+	}
+	ki, err := hex.DecodeString(sub.Ki)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Ki: %w", err)
+	}
+	opc, err := subscriberOPc(sub, ki)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkKeyLengths(sub.Algorithm, ki, opc); err != nil {
+		return nil, err
+	}
+
+	if sub.Algorithm == AlgorithmTUAK {
+		return NewTuak(ki, opc), nil
+	}
+	if sub.Operator != nil {
+		mc, err := MilenageConstantsFromProfile(sub.Operator)
+		if err != nil {
+			return nil, err
+		}
+		if !mc.isDefault() {
+			return newMilenageCustom(ki, opc, mc)
+		}
+	}
+	return &milenageSet{k: ki, opc: opc}, nil
+}
+
+// milenageSet adapts github.com/wmnsk/milenage to AlgorithmSet.
+type milenageSet struct {
+	k, opc []byte
+}
+
+func (m *milenageSet) F1(rand, sqn, amf []byte) ([]byte, error) {
+	if len(sqn) != 6 || len(amf) != 2 {
+		return nil, fmt.Errorf("invalid SQN/AMF length")
+	}
+	sqnVal := binary.BigEndian.Uint64(append([]byte{0, 0}, sqn...))
+	return milenage.NewWithOPc(m.k, m.opc, rand, sqnVal, binary.BigEndian.Uint16(amf)).F1()
+}
+
+func (m *milenageSet) F1Star(rand, sqn, amf []byte) ([]byte, error) {
+	return milenage.NewWithOPc(m.k, m.opc, rand, 0, 0).F1Star(sqn, amf)
+}
+
+func (m *milenageSet) F2345(rand []byte) (res, ck, ik, ak []byte, err error) {
+	return milenage.NewWithOPc(m.k, m.opc, rand, 0, 0).F2345()
+}
+
+func (m *milenageSet) F5Star(rand []byte) ([]byte, error) {
+	return milenage.NewWithOPc(m.k, m.opc, rand, 0, 0).F5Star()
+}
@@ -0,0 +1,136 @@
+package aka
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+)
+
+// MilenageTestSet is one conformance test set of TS 35.208 4.3. All values
+// are hex encoded.
+type MilenageTestSet struct {
+	Name               string
+	K, RAND, SQN, AMF  string
+	OP, OPc            string
+	F1, F1Star, F2     string
+	F3, F4, F5, F5Star string
+}
+
+// TS35208TestSets are test sets 1 to 6 of TS 35.208 4.3.
+var TS35208TestSets = []MilenageTestSet{
+	{
+		Name: "TS 35.208 test set 1",
+		K:    "465b5ce8b199b49faa5f0a2ee238a6bc", RAND: "23553cbe9637a89d218ae64dae47bf35",
+		SQN: "ff9bb4d0b607", AMF: "b9b9",
+		OP: "cdc202d5123e20f62b6d676ac72cb318", OPc: "cd63cb71954a9f4e48a5994e37a02baf",
+		F1: "4a9ffac354dfafb3", F1Star: "01cfaf9ec4e871e9", F2: "a54211d5e3ba50bf",
+		F3: "b40ba9a3c58b2a05bbf0d987b21bf8cb", F4: "f769bcd751044604127672711c6d3441",
+		F5: "aa689c648370", F5Star: "451e8beca43b",
+	},
+	{
+		Name: "TS 35.208 test set 2",
+		K:    "0396eb317b6d1c36f19c1c84cd6ffd16", RAND: "c00d603103dcee52c4478119494202e8",
+		SQN: "fd8eef40df7d", AMF: "af17",
+		OP: "ff53bade17df5d4e793073ce9d7579fa", OPc: "53c15671c60a4b731c55b4a441c0bde2",
+		F1: "5df5b31807e258b0", F1Star: "a8c016e51ef4a343", F2: "d3a628ed988620f0",
+		F3: "58c433ff7a7082acd424220f2b67c556", F4: "21a8c1f929702adb3e738488b9f5c5da",
+		F5: "c47783995f72", F5Star: "30f1197061c1",
+	},
+	{
+		Name: "TS 35.208 test set 3",
+		K:    "fec86ba6eb707ed08905757b1bb44b8f", RAND: "9f7c8d021accf4db213ccff0c7f71a6a",
+		SQN: "9d0277595ffc", AMF: "725c",
+		OP: "dbc59adcb6f9a0ef735477b7fadf8374", OPc: "1006020f0a478bf6b699f15c062e42b3",
+		F1: "9cabc3e99baf7281", F1Star: "95814ba2b3044324", F2: "8011c48c0c214ed2",
+		F3: "5dbdbb2954e8f3cde665b046179a5098", F4: "59a92d3b476a0443487055cf88b2307b",
+		F5: "33484dc2136b", F5Star: "deacdd848cc6",
+	},
+	{
+		Name: "TS 35.208 test set 4",
+		K:    "9e5944aea94b81165c82fbf9f32db751", RAND: "ce83dbc54ac0274a157c17f80d017bd6",
+		SQN: "0b604a81eca8", AMF: "9e09",
+		OP: "223014c5806694c007ca1eeef57f004f", OPc: "a64a507ae1a2a98bb88eb4210135dc87",
+		F1: "74a58220cba84c49", F1Star: "ac2cc74a96871837", F2: "f365cd683cd92e96",
+		F3: "e203edb3971574f5a94b0d61b816345d", F4: "0c4524adeac041c4dd830d20854fc46b",
+		F5: "f0b9c08ad02e", F5Star: "6085a86c6f63",
+	},
+	{
+		Name: "TS 35.208 test set 5",
+		K:    "4ab1deb05ca6ceb051fc98e77d026a84", RAND: "74b0cd6031a1c8339b2b6ce2b8c4a186",
+		SQN: "e880a1b580b6", AMF: "9f07",
+		OP: "2d16c5cd1fdf6b22383584e3bef2a8d8", OPc: "dcf07cbd51855290b92a07a9891e523e",
+		F1: "49e785dd12626ef2", F1Star: "9e85790336bb3fa2", F2: "5860fc1bce351e7e",
+		F3: "7657766b373d1c2138f307e3de9242f9", F4: "1c42e960d89b8fa99f2744e0708ccb53",
+		F5: "31e11a609118", F5Star: "fe2555e54aa9",
+	},
+	{
+		Name: "TS 35.208 test set 6",
+		K:    "6c38a116ac280c454f59332ee35c8c4f", RAND: "ee6466bc96202c5a557abbeff8babf63",
+		SQN: "414b98222181", AMF: "4464",
+		OP: "1ba00a1a7c6700ac8c3ff3e96ad08725", OPc: "3803ef5363b947c6aaa225e58fae3934",
+		F1: "078adfb488241a57", F1Star: "80246b8d0186bcf1", F2: "16c8233f05a0ac28",
+		F3: "3f8c7587fe8e4b233af676aede30ba3b", F4: "a7466cc1e6b2a1337d49d3b66e95d7b4",
+		F5: "45b0f69ab06c", F5Star: "1f53cd2b1113",
+	},
+}
+
+// Run checks OPc and f1, f1*, f2-f5, f5* of the test set against both
+// Milenage implementations used for vector generation.
+func (ts *MilenageTestSet) Run() error {
+	v := make(map[string][]byte)
+	for name, s := range map[string]string{
+		"K": ts.K, "RAND": ts.RAND, "SQN": ts.SQN, "AMF": ts.AMF, "OP": ts.OP, "OPc": ts.OPc,
+		"f1": ts.F1, "f1*": ts.F1Star, "f2": ts.F2, "f3": ts.F3, "f4": ts.F4, "f5": ts.F5, "f5*": ts.F5Star,
+	} {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			return fmt.Errorf("%s: invalid %s: %w", ts.Name, name, err)
+		}
+		v[name] = b
+	}
+
+	opc, err := computeOPc(AlgorithmMilenage, v["K"], v["OP"])
+	if err != nil {
+		return fmt.Errorf("%s: OPc: %w", ts.Name, err)
+	}
+	if !bytes.Equal(opc, v["OPc"]) {
+		return fmt.Errorf("%s: OPc mismatch: got %x, want %x", ts.Name, opc, v["OPc"])
+	}
+
+	custom, err := newMilenageCustom(v["K"], v["OPc"], &DefaultMilenageConstants)
+	if err != nil {
+		return fmt.Errorf("%s: %w", ts.Name, err)
+	}
+	for _, alg := range []AlgorithmSet{&milenageSet{k: v["K"], opc: v["OPc"]}, custom} {
+		got := make(map[string][]byte)
+		var err error
+		if got["f1"], err = alg.F1(v["RAND"], v["SQN"], v["AMF"]); err != nil {
+			return fmt.Errorf("%s: f1: %w", ts.Name, err)
+		}
+		if got["f1*"], err = alg.F1Star(v["RAND"], v["SQN"], v["AMF"]); err != nil {
+			return fmt.Errorf("%s: f1*: %w", ts.Name, err)
+		}
+		if got["f2"], got["f3"], got["f4"], got["f5"], err = alg.F2345(v["RAND"]); err != nil {
+			return fmt.Errorf("%s: f2-f5: %w", ts.Name, err)
+		}
+		if got["f5*"], err = alg.F5Star(v["RAND"]); err != nil {
+			return fmt.Errorf("%s: f5*: %w", ts.Name, err)
+		}
+		for _, name := range []string{"f1", "f1*", "f2", "f3", "f4", "f5", "f5*"} {
+			if !bytes.Equal(got[name], v[name]) {
+				return fmt.Errorf("%s: %s mismatch (%T): got %x, want %x", ts.Name, name, alg, got[name], v[name])
+			}
+		}
+	}
+	return nil
+}
+
+// SelfTest runs all TS35208TestSets and returns the first failure.
+func SelfTest() error {
+	for i := range TS35208TestSets {
+		if err := TS35208TestSets[i].Run(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package aka
+
+import "testing"
+
+func TestTS35208TestSets(t *testing.T) {
+	if len(TS35208TestSets) != 6 {
+		t.Fatalf("Expected 6 test sets, got %d", len(TS35208TestSets))
+	}
+	for _, ts := range TS35208TestSets {
+		t.Run(ts.Name, func(t *testing.T) {
+			if err := ts.Run(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	if err := SelfTest(); err != nil {
+		t.Errorf("SelfTest failed: %v", err)
+	}
+}
+
+func TestMilenageTestSetDetectsMismatch(t *testing.T) {
+	ts := TS35208TestSets[0]
+	ts.F5Star = "451e8beca43c"
+	if err := ts.Run(); err == nil {
+		t.Error("Expected f5* mismatch to be reported")
+	}
+
+	ts = TS35208TestSets[0]
+	ts.OPc = "cd63cb71954a9f4e48a5994e37a02bae"
+	if err := ts.Run(); err == nil {
+		t.Error("Expected OPc mismatch to be reported")
+	}
+}
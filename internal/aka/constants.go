@@ -0,0 +1,185 @@
+package aka
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+
+	"aka-server/internal/model"
+)
+
+// MilenageConstants are the rotation amounts r1..r5 (in bits) and the
+// 128-bit constants c1..c5 of TS 35.206 4.1.
+type MilenageConstants struct {
+	R [5]int
+	C [5][]byte
+}
+
+// DefaultMilenageConstants are the values given in TS 35.206 4.1.
+var DefaultMilenageConstants = MilenageConstants{
+	R: [5]int{64, 0, 32, 64, 96},
+	C: [5][]byte{
+		make([]byte, 16),
+		append(make([]byte, 15), 0x01),
+		append(make([]byte, 15), 0x02),
+		append(make([]byte, 15), 0x04),
+		append(make([]byte, 15), 0x08),
+	},
+}
+
+// MilenageConstantsFromProfile returns the constants defined by an operator
+// profile. Unset values fall back to DefaultMilenageConstants.
+func MilenageConstantsFromProfile(p *model.OperatorProfile) (*MilenageConstants, error) {
+	mc := DefaultMilenageConstants
+	cs := [5]string{p.C1, p.C2, p.C3, p.C4, p.C5}
+	rs := [5]*int{p.R1, p.R2, p.R3, p.R4, p.R5}
+	for i := 0; i < 5; i++ {
+		if cs[i] != "" {
+			c, err := hex.DecodeString(cs[i])
+			if err != nil || len(c) != 16 {
+				return nil, fmt.Errorf("invalid c%d: %q", i+1, cs[i])
+			}
+			mc.C[i] = c
+		}
+		if rs[i] != nil {
+			if *rs[i] < 0 || *rs[i] > 127 {
+				return nil, fmt.Errorf("invalid r%d: %d", i+1, *rs[i])
+			}
+			mc.R[i] = *rs[i]
+		}
+	}
+	return &mc, nil
+}
+
+// isDefault reports whether mc equals DefaultMilenageConstants.
+func (mc *MilenageConstants) isDefault() bool {
+	for i := 0; i < 5; i++ {
+		if mc.R[i] != DefaultMilenageConstants.R[i] || string(mc.C[i]) != string(DefaultMilenageConstants.C[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// milenageCustom is a Milenage implementation (TS 35.206) with configurable
+// r and c values. It is only used when an operator profile changes them;
+// the default constants go through github.com/wmnsk/milenage.
+type milenageCustom struct {
+	block cipher.Block
+	opc   []byte
+	mc    *MilenageConstants
+}
+
+func newMilenageCustom(k, opc []byte, mc *MilenageConstants) (*milenageCustom, error) {
+	if len(k) != 16 {
+		return nil, fmt.Errorf("length of K should be 16, got: %d", len(k))
+	}
+	if len(opc) != 16 {
+		return nil, fmt.Errorf("length of OPc should be 16, got: %d", len(opc))
+	}
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	return &milenageCustom{block: block, opc: opc, mc: mc}, nil
+}
+
+// temp computes TEMP = E_K(RAND ^ OPc).
+func (m *milenageCustom) temp(rand []byte) ([]byte, error) {
+	if len(rand) != 16 {
+		return nil, fmt.Errorf("length of RAND should be 16, got: %d", len(rand))
+	}
+	t := xor16(rand, m.opc)
+	m.block.Encrypt(t, t)
+	return t, nil
+}
+
+// out computes OUTi = E_K(rot(x ^ OPc, ri) ^ ci) ^ OPc, where x is TEMP for
+// i = 2..5. For i = 1 the caller passes IN1 and XORs TEMP in via extra.
+func (m *milenageCustom) out(i int, x, extra []byte) []byte {
+	b := rotl128(xor16(x, m.opc), m.mc.R[i-1])
+	b = xor16(b, m.mc.C[i-1])
+	if extra != nil {
+		b = xor16(b, extra)
+	}
+	m.block.Encrypt(b, b)
+	return xor16(b, m.opc)
+}
+
+func (m *milenageCustom) out1(rand, sqn, amf []byte) ([]byte, error) {
+	if len(sqn) != 6 || len(amf) != 2 {
+		return nil, fmt.Errorf("invalid SQN/AMF length")
+	}
+	t, err := m.temp(rand)
+	if err != nil {
+		return nil, err
+	}
+	in1 := make([]byte, 0, 16)
+	in1 = append(in1, sqn...)
+	in1 = append(in1, amf...)
+	in1 = append(in1, sqn...)
+	in1 = append(in1, amf...)
+	return m.out(1, in1, t), nil
+}
+
+func (m *milenageCustom) F1(rand, sqn, amf []byte) ([]byte, error) {
+	o, err := m.out1(rand, sqn, amf)
+	if err != nil {
+		return nil, err
+	}
+	return o[:8], nil
+}
+
+func (m *milenageCustom) F1Star(rand, sqn, amf []byte) ([]byte, error) {
+	o, err := m.out1(rand, sqn, amf)
+	if err != nil {
+		return nil, err
+	}
+	return o[8:], nil
+}
+
+func (m *milenageCustom) F2345(rand []byte) (res, ck, ik, ak []byte, err error) {
+	t, err := m.temp(rand)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	out2 := m.out(2, t, nil)
+	return out2[8:], m.out(3, t, nil), m.out(4, t, nil), out2[:6], nil
+}
+
+func (m *milenageCustom) F5Star(rand []byte) ([]byte, error) {
+	t, err := m.temp(rand)
+	if err != nil {
+		return nil, err
+	}
+	return m.out(5, t, nil)[:6], nil
+}
+
+func xor16(a, b []byte) []byte {
+	out := make([]byte, 16)
+	for i := range out {
+		out[i] = a[i] ^ b[i]
+	}
+	return out
+}
+
+// rotl128 cyclically rotates a 128-bit value r bits towards the most
+// significant end, as rot(x, r) in TS 35.206.
+func rotl128(x []byte, r int) []byte {
+	hi := binary.BigEndian.Uint64(x[:8])
+	lo := binary.BigEndian.Uint64(x[8:])
+	r %= 128
+	if r >= 64 {
+		hi, lo = lo, hi
+		r -= 64
+	}
+	if r > 0 {
+		hi, lo = hi<<r|lo>>(64-r), lo<<r|hi>>(64-r)
+	}
+	out := make([]byte, 16)
+	binary.BigEndian.PutUint64(out[:8], hi)
+	binary.BigEndian.PutUint64(out[8:], lo)
+	return out
+}
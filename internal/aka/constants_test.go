@@ -0,0 +1,168 @@
+package aka
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"aka-server/internal/model"
+)
+
+func TestMilenageCustomDefaultMatchesLibrary(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	buf := func(n int) []byte {
+		b := make([]byte, n)
+		r.Read(b)
+		return b
+	}
+	for i := 0; i < 100; i++ {
+		k, opc, rnd, sqn, amf := buf(16), buf(16), buf(16), buf(6), buf(2)
+		lib := &milenageSet{k: k, opc: opc}
+		own, err := newMilenageCustom(k, opc, &DefaultMilenageConstants)
+		if err != nil {
+			t.Fatalf("newMilenageCustom failed: %v", err)
+		}
+
+		for _, f := range []struct {
+			name string
+			fn   func(AlgorithmSet) ([]byte, error)
+		}{
+			{"f1", func(a AlgorithmSet) ([]byte, error) { return a.F1(rnd, sqn, amf) }},
+			{"f1*", func(a AlgorithmSet) ([]byte, error) { return a.F1Star(rnd, sqn, amf) }},
+			{"f5*", func(a AlgorithmSet) ([]byte, error) { return a.F5Star(rnd) }},
+		} {
+			want, err1 := f.fn(lib)
+			got, err2 := f.fn(own)
+			if err1 != nil || err2 != nil {
+				t.Fatalf("%s failed: %v / %v", f.name, err1, err2)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("%s mismatch: got %x, want %x", f.name, got, want)
+			}
+		}
+
+		wRes, wCk, wIk, wAk, _ := lib.F2345(rnd)
+		gRes, gCk, gIk, gAk, _ := own.F2345(rnd)
+		if !bytes.Equal(gRes, wRes) || !bytes.Equal(gCk, wCk) || !bytes.Equal(gIk, wIk) || !bytes.Equal(gAk, wAk) {
+			t.Fatalf("f2-f5 mismatch for K=%x OPc=%x RAND=%x", k, opc, rnd)
+		}
+	}
+}
+
+func TestRotl128(t *testing.T) {
+	one := append(make([]byte, 15), 0x01)
+	tests := []struct {
+		r    int
+		want string
+	}{
+		{0, "00000000000000000000000000000001"},
+		{1, "00000000000000000000000000000002"},
+		{63, "00000000000000008000000000000000"},
+		{64, "00000000000000010000000000000000"},
+		{100, "00000010000000000000000000000000"},
+		{127, "80000000000000000000000000000000"},
+	}
+	for _, tt := range tests {
+		if got := hex.EncodeToString(rotl128(one, tt.r)); got != tt.want {
+			t.Errorf("rot(1, %d) = %s, want %s", tt.r, got, tt.want)
+		}
+	}
+}
+
+func TestMilenageConstantsFromProfile(t *testing.T) {
+	r1 := 64
+	mc, err := MilenageConstantsFromProfile(&model.OperatorProfile{C2: "00000000000000000000000000000001", R1: &r1})
+	if err != nil {
+		t.Fatalf("MilenageConstantsFromProfile failed: %v", err)
+	}
+	if !mc.isDefault() {
+		t.Error("Expected explicit default values to be treated as default")
+	}
+
+	bad := 128
+	if _, err := MilenageConstantsFromProfile(&model.OperatorProfile{R3: &bad}); err == nil {
+		t.Error("Expected error for r3 out of range")
+	}
+	if _, err := MilenageConstantsFromProfile(&model.OperatorProfile{C4: "0102"}); err == nil {
+		t.Error("Expected error for short c4")
+	}
+}
+
+func TestGenerateVectorOperatorProfile(t *testing.T) {
+	r2, r5 := 8, 17
+	profile := &model.OperatorProfile{
+		ID: "mvno",
+		C1: "0f0e0d0c0b0a09080706050403020100",
+		C3: "000102030405060708090a0b0c0d0e0f",
+		R2: &r2,
+		R5: &r5,
+	}
+	sub := &model.Subscriber{
+		Ki:       "00112233445566778899aabbccddeeff",
+		Opc:      "000102030405060708090a0b0c0d0e0f",
+		SQN:      "000000000020",
+		AMF:      "8000",
+		Operator: profile,
+	}
+
+	alg, err := NewAlgorithmSet(sub)
+	if err != nil {
+		t.Fatalf("NewAlgorithmSet failed: %v", err)
+	}
+	if _, ok := alg.(*milenageCustom); !ok {
+		t.Fatalf("Expected custom Milenage, got %T", alg)
+	}
+
+	vec, _, err := GenerateVector(sub)
+	if err != nil {
+		t.Fatalf("GenerateVector failed: %v", err)
+	}
+	q, err := decodeQuintet(vec)
+	if err != nil {
+		t.Fatalf("decodeQuintet failed: %v", err)
+	}
+
+	// The vector must verify with the profile's constants and not with the
+	// defaults.
+	std := &milenageSet{k: mustHex(t, sub.Ki), opc: mustHex(t, sub.Opc)}
+	for _, tc := range []struct {
+		alg   AlgorithmSet
+		match bool
+	}{{alg, true}, {std, false}} {
+		res, _, _, ak, _ := tc.alg.F2345(q.rand)
+		sqn := make([]byte, 6)
+		for i := range sqn {
+			sqn[i] = q.autn[i] ^ ak[i]
+		}
+		mac, _ := tc.alg.F1(q.rand, sqn, q.autn[6:8])
+		if ok := bytes.Equal(mac, q.autn[8:]) && bytes.Equal(res, q.res); ok != tc.match {
+			t.Errorf("%T: MAC/RES match = %v, want %v", tc.alg, ok, tc.match)
+		}
+	}
+
+	// Resync with an AUTS built from the same constants.
+	sqnMS := sqnToBytes(20 << 5)
+	akStar, _ := alg.F5Star(q.rand)
+	macS, _ := alg.F1Star(q.rand, sqnMS, []byte{0, 0})
+	auts := make([]byte, 0, 14)
+	for i := range sqnMS {
+		auts = append(auts, sqnMS[i]^akStar[i])
+	}
+	auts = append(auts, macS...)
+	_, newSQN, err := Resync(sub, hex.EncodeToString(q.rand), hex.EncodeToString(auts))
+	if err != nil {
+		t.Fatalf("Resync failed: %v", err)
+	}
+	if got := seqOf(t, newSQN); got != 21 {
+		t.Errorf("Expected SEQ 21 after resync, got %d", got)
+	}
+
+	// The same AUTS is rejected without the profile.
+	plain := *sub
+	plain.Operator = nil
+	if _, _, err := Resync(&plain, hex.EncodeToString(q.rand), hex.EncodeToString(auts)); err == nil || !strings.Contains(err.Error(), "MAC") {
+		t.Errorf("Expected MAC failure without the profile, got %v", err)
+	}
+}
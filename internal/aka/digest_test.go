@@ -0,0 +1,58 @@
+package aka
+
+import "testing"
+
+func TestDeriveDigest(t *testing.T) {
+	const (
+		username = "001010000000001@ims.mnc001.mcc001.3gppnetwork.org"
+		realm    = "ims.mnc001.mcc001.3gppnetwork.org"
+		nonce    = "I1U8vpY3qJ0hiuZNrke/NVXzKLQ1d7m5Sp/6w1Tfr7M="
+	)
+
+	v1, err := DeriveDigest(testSet1Vector, "", username, realm)
+	if err != nil {
+		t.Fatalf("DeriveDigest AKAv1 failed: %v", err)
+	}
+	if v1.Algorithm != DigestAKAv1MD5 || v1.Nonce != nonce || v1.Password != "" {
+		t.Errorf("Unexpected AKAv1 vector %+v", v1)
+	}
+	if v1.HA1 != "da00069eb0de6587da7e09ef503c29db" {
+		t.Errorf("Unexpected AKAv1 HA1 %s", v1.HA1)
+	}
+
+	v2, err := DeriveDigest(testSet1Vector, DigestAKAv2MD5, username, realm)
+	if err != nil {
+		t.Fatalf("DeriveDigest AKAv2 failed: %v", err)
+	}
+	if v2.Password != "shzt3q8CWaZnCAWqs3WmEQ==" {
+		t.Errorf("Unexpected AKAv2 password %s", v2.Password)
+	}
+	if v2.HA1 != "5f16eac3c7cb97f22cbfb39ba2210495" {
+		t.Errorf("Unexpected AKAv2 HA1 %s", v2.HA1)
+	}
+
+	if v, _ := DeriveDigest(testSet1Vector, "", "", ""); v.HA1 != "" {
+		t.Error("HA1 must be omitted without a username")
+	}
+	if _, err := DeriveDigest(testSet1Vector, "AKAv1-SHA-256", "", ""); err == nil {
+		t.Error("Expected error for unsupported digest algorithm")
+	}
+}
+
+func TestDigestResyncParameters(t *testing.T) {
+	rand, err := DigestNonceRand("I1U8vpY3qJ0hiuZNrke/NVXzKLQ1d7m5Sp/6w1Tfr7M=")
+	if err != nil || rand != testSet1Vector.Rand {
+		t.Errorf("DigestNonceRand = %s, %v", rand, err)
+	}
+	if _, err := DigestNonceRand("I1U8vpY3qJ0hiuZNrke/NQ=="); err == nil {
+		t.Error("Expected error for a nonce shorter than RAND || AUTN")
+	}
+
+	auts, err := DecodeDigestAUTS("3q2+78r+AQIDBAUGBwg=")
+	if err != nil || auts != "deadbeefcafe0102030405060708" {
+		t.Errorf("DecodeDigestAUTS = %s, %v", auts, err)
+	}
+	if _, err := DecodeDigestAUTS("deadbeefcafe0102030405060708"); err == nil {
+		t.Error("Expected error for hex AUTS")
+	}
+}
@@ -0,0 +1,167 @@
+package aka
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"math/bits"
+)
+
+// EAPKeys is the key hierarchy of a full EAP-AKA (RFC 4187 7) or EAP-AKA'
+// (RFC 9048 3.3) authentication. MK is only used by EAP-AKA and K_re only by
+// EAP-AKA'.
+type EAPKeys struct {
+	MK    string `json:"mk,omitempty"`
+	KEncr string `json:"k_encr"`
+	KAut  string `json:"k_aut"`
+	KRe   string `json:"k_re,omitempty"`
+	MSK   string `json:"msk"`
+	EMSK  string `json:"emsk"`
+}
+
+// EAPVector is a quintet together with the EAP keys derived from it.
+type EAPVector struct {
+	AuthVector
+	EAPKeys
+}
+
+// NewEAPVector derives the EAP-AKA keys of vec for the EAP identity, or the
+// EAP-AKA' keys if prime is set. For EAP-AKA', vec must already carry CK'
+// and IK' (see DeriveAKAPrime).
+func NewEAPVector(vec *AuthVector, identity string, prime bool) (*EAPVector, error) {
+	derive := DeriveEAPAKAKeys
+	if prime {
+		derive = DeriveEAPAKAPrimeKeys
+	}
+	keys, err := derive(vec, identity)
+	if err != nil {
+		return nil, err
+	}
+	return &EAPVector{AuthVector: *vec, EAPKeys: *keys}, nil
+}
+
+// DeriveEAPAKAKeys derives MK = SHA1(Identity | IK | CK) and expands it with
+// the FIPS 186-2 PRF into K_encr, K_aut, MSK and EMSK (RFC 4187 7).
+func DeriveEAPAKAKeys(vec *AuthVector, identity string) (*EAPKeys, error) {
+	if identity == "" {
+		return nil, fmt.Errorf("EAP identity is required")
+	}
+	q, err := decodeQuintet(vec)
+	if err != nil {
+		return nil, err
+	}
+	h := sha1.New()
+	h.Write([]byte(identity))
+	h.Write(q.ik)
+	h.Write(q.ck)
+	mk := h.Sum(nil)
+
+	out := fips186PRF(mk, 160)
+	return &EAPKeys{
+		MK:    hex.EncodeToString(mk),
+		KEncr: hex.EncodeToString(out[0:16]),
+		KAut:  hex.EncodeToString(out[16:32]),
+		MSK:   hex.EncodeToString(out[32:96]),
+		EMSK:  hex.EncodeToString(out[96:160]),
+	}, nil
+}
+
+// DeriveEAPAKAPrimeKeys derives MK = PRF'(IK' | CK', "EAP-AKA'" | Identity)
+// and splits it into K_encr, K_aut, K_re, MSK and EMSK (RFC 9048 3.3). vec
+// carries CK' and IK'.
+func DeriveEAPAKAPrimeKeys(vec *AuthVector, identity string) (*EAPKeys, error) {
+	if identity == "" {
+		return nil, fmt.Errorf("EAP identity is required")
+	}
+	q, err := decodeQuintet(vec)
+	if err != nil {
+		return nil, err
+	}
+	key := append(append([]byte{}, q.ik...), q.ck...)
+	mk := prfPrime(key, []byte("EAP-AKA'"+identity), 208)
+	return &EAPKeys{
+		KEncr: hex.EncodeToString(mk[0:16]),
+		KAut:  hex.EncodeToString(mk[16:48]),
+		KRe:   hex.EncodeToString(mk[48:80]),
+		MSK:   hex.EncodeToString(mk[80:144]),
+		EMSK:  hex.EncodeToString(mk[144:208]),
+	}, nil
+}
+
+// prfPrime is PRF' of RFC 9048 3.4.1: T1 | T2 | ... with
+// T1 = HMAC-SHA-256(K, S | 0x01) and Tn = HMAC-SHA-256(K, Tn-1 | S | n).
+func prfPrime(key, s []byte, n int) []byte {
+	var out, t []byte
+	for i := byte(1); len(out) < n; i++ {
+		mac := hmac.New(sha256.New, key)
+		mac.Write(t)
+		mac.Write(s)
+		mac.Write([]byte{i})
+		t = mac.Sum(nil)
+		out = append(out, t...)
+	}
+	return out[:n]
+}
+
+// fips186PRF is the pseudo-random function of FIPS 186-2 change notice 1,
+// Appendix 3.1, as used by RFC 4187 7: XSEED is zero, and each iteration
+// produces two 160-bit outputs with G, the SHA-1 compression function.
+func fips186PRF(xkey []byte, n int) []byte {
+	mod := new(big.Int).Lsh(big.NewInt(1), 160)
+	x := new(big.Int).SetBytes(xkey)
+	var out []byte
+	for len(out) < n {
+		for i := 0; i < 2; i++ {
+			w := sha1G(x.FillBytes(make([]byte, 20)))
+			out = append(out, w...)
+			// XKEY = (1 + XKEY + w) mod 2^160
+			x.Add(x, new(big.Int).SetBytes(w))
+			x.Add(x, big.NewInt(1))
+			x.Mod(x, mod)
+		}
+	}
+	return out[:n]
+}
+
+// sha1G is G(t, c) of FIPS 186-2 Appendix 3.3: the SHA-1 compression
+// function applied to the initial state and c zero-padded to one block,
+// without the usual message padding.
+func sha1G(c []byte) []byte {
+	var block [64]byte
+	copy(block[:], c)
+	var w [80]uint32
+	for i := 0; i < 16; i++ {
+		w[i] = binary.BigEndian.Uint32(block[4*i:])
+	}
+	for i := 16; i < 80; i++ {
+		w[i] = bits.RotateLeft32(w[i-3]^w[i-8]^w[i-14]^w[i-16], 1)
+	}
+
+	h := [5]uint32{0x67452301, 0xEFCDAB89, 0x98BADCFE, 0x10325476, 0xC3D2E1F0}
+	a, b, c2, d, e := h[0], h[1], h[2], h[3], h[4]
+	for i := 0; i < 80; i++ {
+		var f, k uint32
+		switch {
+		case i < 20:
+			f, k = (b&c2)|(^b&d), 0x5A827999
+		case i < 40:
+			f, k = b^c2^d, 0x6ED9EBA1
+		case i < 60:
+			f, k = (b&c2)|(b&d)|(c2&d), 0x8F1BBCDC
+		default:
+			f, k = b^c2^d, 0xCA62C1D6
+		}
+		t := bits.RotateLeft32(a, 5) + f + e + k + w[i]
+		a, b, c2, d, e = t, a, bits.RotateLeft32(b, 30), c2, d
+	}
+
+	out := make([]byte, 20)
+	for i, v := range []uint32{a, b, c2, d, e} {
+		binary.BigEndian.PutUint32(out[4*i:], h[i]+v)
+	}
+	return out
+}
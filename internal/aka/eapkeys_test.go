@@ -0,0 +1,82 @@
+package aka
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestFIPS186PRF(t *testing.T) {
+	// RFC 4186 Appendix A: MK and the keys the FIPS 186-2 PRF expands it to.
+	// EAP-AKA uses the same PRF.
+	out := fips186PRF(mustHex(t, "e576d5ca332e9930018bf1baee2763c795b3c712"), 160)
+	checks := []struct {
+		name string
+		got  []byte
+		want string
+	}{
+		{"K_encr", out[0:16], "536e5ebc4465582aa6a8ec9986ebb620"},
+		{"K_aut", out[16:32], "25af1942efcbf4bc72b3943421f2a974"},
+		{"MSK", out[32:96], "39d45aeaf4e30601983e972b6cfd46d1c363773365690d09cd44976b525f47d3a60a985e955c53b090b2e4b73719196a402542968fd14a888f46b9a7886e4488"},
+		{"EMSK", out[96:160], "5949eab0fff69d52315c6c634fd14a7f0d52023d56f79698fa6596abeed4f93fbb48eb534d985414ceed0d9a8ed33c387c9dfdab92ffbdf240fcecf65a2c93b9"},
+	}
+	for _, c := range checks {
+		if hex.EncodeToString(c.got) != c.want {
+			t.Errorf("%s: got %x, want %s", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestDeriveEAPAKAKeys(t *testing.T) {
+	keys, err := DeriveEAPAKAKeys(testSet1Vector, "0001010123456789@wlan.mnc001.mcc001.3gppnetwork.org")
+	if err != nil {
+		t.Fatalf("DeriveEAPAKAKeys failed: %v", err)
+	}
+	// MK = SHA1(Identity | IK | CK) over the TS 35.208 test set 1 CK and IK,
+	// and its FIPS 186-2 PRF expansion, computed with a separate Python
+	// implementation that reproduces RFC 4186 Appendix A.
+	want := EAPKeys{
+		MK:    "243610c4bc1f713cd7a0f118f6a43d7a5cb36e0f",
+		KEncr: "5600809fb71b48df8539b7a3151931aa",
+		KAut:  "695f9d8fda128349ba9068abf2901a84",
+		MSK:   "34330f007f638a0c975eb5add36cce33412587ec61763ee9dbb74aec8d2dbee56111c20c1aafd03e4d9d081a789de9a620563e470244ae5ea55c517a7c9a6eeb",
+		EMSK:  "faebb30ea26d547f5a8d4bebe2cc357aba71eefc22aa59442ca3b788648bc9d1c522d1bc82ac2fac01690fd5d62f0f81b5969dd788c60736096c18a490e1de58",
+	}
+	if *keys != want {
+		t.Errorf("got %+v, want %+v", *keys, want)
+	}
+	if _, err := DeriveEAPAKAKeys(testSet1Vector, ""); err == nil {
+		t.Error("Expected error without an identity")
+	}
+}
+
+func TestDeriveEAPAKAPrimeKeys(t *testing.T) {
+	// RFC 5448 Appendix C, test case 1.
+	vec := &AuthVector{
+		Rand: "81e92b6c0ee0e12ebceba8d92a99dfa5",
+		Autn: "bb52e91c747ac3ab2a5c23d15ee351d5",
+		Xres: "28d7b0f2a2ec3de5",
+		Ck:   "5349fbe098649f948f5d2e973a81c00f",
+		Ik:   "9744871ad32bf9bbd1dd5ce54e3e2e5a",
+	}
+	prime, err := DeriveAKAPrime(vec, "WLAN")
+	if err != nil {
+		t.Fatalf("DeriveAKAPrime failed: %v", err)
+	}
+	ev, err := NewEAPVector(prime, "0555444333222111", true)
+	if err != nil {
+		t.Fatalf("NewEAPVector failed: %v", err)
+	}
+	want := EAPKeys{
+		KEncr: "766fa0a6c317174b812d52fbcd11a179",
+		KAut:  "0842ea722ff6835bfa2032499fc3ec23c2f0e388b4f07543ffc677f1696d71ea",
+		KRe:   "cf83aa8bc7e0aced892acc98e76a9b2095b558c7795c7094715cb3393aa7d17a",
+		MSK:   "67c42d9aa56c1b79e295e3459fc3d187d42be0bf818d3070e362c5e967a4d544e8ecfe19358ab3039aff03b7c930588c055babee58a02650b067ec4e9347c75a",
+		EMSK:  "f861703cd775590e16c7679ea3874ada866311de290764d760cf76df647ea01c313f69924bdd7650ca9bac141ea075c4ef9e8029c0e290cdbad5638b63bc23fb",
+	}
+	if ev.EAPKeys != want {
+		t.Errorf("got %+v, want %+v", ev.EAPKeys, want)
+	}
+	if ev.Ck != "0093962d0dd84aa5684b045c9edffa04" || ev.Ik != "ccfc230ca74fcc96c0a5d61164f5a76c" {
+		t.Errorf("Unexpected CK'/IK' %s/%s", ev.Ck, ev.Ik)
+	}
+}
@@ -0,0 +1,63 @@
+package aka
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// KDF FC value for KASME derivation (TS 33.401 Annex A.2).
+const fcKASME = 0x10
+
+// EPSVector is an EPS authentication vector (TS 33.401 6.1.2).
+type EPSVector struct {
+	Rand  string `json:"rand"`
+	Xres  string `json:"xres"`
+	Autn  string `json:"autn"`
+	Kasme string `json:"kasme"`
+}
+
+// PLMNID encodes MCC and MNC as the 3-byte PLMN identity of TS 24.008
+// 10.5.1.13, which is the SN id used in KASME derivation. A two-digit MNC is
+// encoded with filler digit F.
+func PLMNID(mcc, mnc string) ([]byte, error) {
+	if !IsDigits(mcc, 3, 3) || !IsDigits(mnc, 2, 3) {
+		return nil, fmt.Errorf("invalid MCC/MNC: %s/%s", mcc, mnc)
+	}
+	d := func(s string, i int) byte { return s[i] - '0' }
+	mnc3 := byte(0xF)
+	if len(mnc) == 3 {
+		mnc3 = d(mnc, 2)
+	}
+	return []byte{
+		d(mcc, 1)<<4 | d(mcc, 0),
+		mnc3<<4 | d(mcc, 2),
+		d(mnc, 1)<<4 | d(mnc, 0),
+	}, nil
+}
+
+// DeriveEPS turns a UMTS quintet into an EPS vector for the serving network
+// identified by mcc/mnc (TS 33.401 Annex A.2). The quintet must have been
+// generated with the AMF separation bit set.
+func DeriveEPS(vec *AuthVector, mcc, mnc string) (*EPSVector, error) {
+	snID, err := PLMNID(mcc, mnc)
+	if err != nil {
+		return nil, err
+	}
+	q, err := decodeQuintet(vec)
+	if err != nil {
+		return nil, err
+	}
+	if q.autn[6]&0x80 == 0 {
+		return nil, fmt.Errorf("AMF separation bit not set")
+	}
+
+	key := append(append([]byte{}, q.ck...), q.ik...)
+	kasme := kdf(key, fcKASME, snID, q.autn[:6])
+
+	return &EPSVector{
+		Rand:  vec.Rand,
+		Xres:  vec.Xres,
+		Autn:  vec.Autn,
+		Kasme: hex.EncodeToString(kasme),
+	}, nil
+}
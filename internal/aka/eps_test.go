@@ -0,0 +1,55 @@
+package aka
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestPLMNID(t *testing.T) {
+	cases := []struct {
+		mcc, mnc string
+		want     string
+	}{
+		{"001", "01", "00f110"},
+		{"310", "410", "130014"},
+		{"440", "10", "44f001"},
+	}
+	for _, c := range cases {
+		got, err := PLMNID(c.mcc, c.mnc)
+		if err != nil {
+			t.Fatalf("PLMNID(%s, %s) failed: %v", c.mcc, c.mnc, err)
+		}
+		if hex.EncodeToString(got) != c.want {
+			t.Errorf("PLMNID(%s, %s) = %x, want %s", c.mcc, c.mnc, got, c.want)
+		}
+	}
+	if _, err := PLMNID("01", "01"); err == nil {
+		t.Error("Expected error for 2-digit MCC")
+	}
+}
+
+func TestDeriveEPS(t *testing.T) {
+	// Test set 1 has AMF b9b9, which already has the separation bit set.
+	v, err := DeriveEPS(testSet1Vector, "001", "01")
+	if err != nil {
+		t.Fatalf("DeriveEPS failed: %v", err)
+	}
+
+	// KASME: FC=0x10, P0=SN id 00f110, P1=SQN^AK (TS 33.401 Annex A.2).
+	// Computed with an independent HMAC-SHA256 implementation of the
+	// TS 33.220 Annex B.2 KDF.
+	if v.Kasme != "48579af8781c742d5120e6ed8ccac13193f38c53ab7aa69396f49ca6e1b0562d" {
+		t.Errorf("Unexpected KASME %s", v.Kasme)
+	}
+	if v.Xres != testSet1Vector.Xres || v.Rand != testSet1Vector.Rand || v.Autn != testSet1Vector.Autn {
+		t.Errorf("RAND/XRES/AUTN must be passed through unchanged")
+	}
+}
+
+func TestDeriveEPSRequiresSeparationBit(t *testing.T) {
+	vec := *testSet1Vector
+	vec.Autn = "55f328b435773939" + vec.Autn[16:] // AMF 3939
+	if _, err := DeriveEPS(&vec, "001", "01"); err == nil {
+		t.Error("Expected error for AMF without separation bit")
+	}
+}
@@ -0,0 +1,97 @@
+package aka
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// KDF FC values from TS 33.501 Annex A.
+const (
+	fcKAUSF   = 0x6A
+	fcRESStar = 0x6B
+	fcKSEAF   = 0x6C
+)
+
+// Vector5G is a 5G home environment authentication vector (TS 33.501 6.1.3.2)
+// together with the SEAF-side values derived from it.
+type Vector5G struct {
+	Rand      string `json:"rand"`
+	Autn      string `json:"autn"`
+	XresStar  string `json:"xres_star"`
+	HxresStar string `json:"hxres_star"`
+	Kausf     string `json:"kausf"`
+	Kseaf     string `json:"kseaf"`
+}
+
+// ServingNetworkName builds the 5G serving network name (TS 24.501 9.12.1)
+// for the given MCC and MNC. Two-digit MNCs are zero-padded.
+func ServingNetworkName(mcc, mnc string) (string, error) {
+	if len(mnc) == 2 {
+		mnc = "0" + mnc
+	}
+	if !IsDigits(mcc, 3, 3) || !IsDigits(mnc, 3, 3) {
+		return "", fmt.Errorf("invalid MCC/MNC: %s/%s", mcc, mnc)
+	}
+	return fmt.Sprintf("5G:mnc%s.mcc%s.3gppnetwork.org", mnc, mcc), nil
+}
+
+// ValidateServingNetworkName checks that snn has the "5G:" prefix required by
+// TS 33.501 6.1.1.4.
+func ValidateServingNetworkName(snn string) error {
+	if !strings.HasPrefix(snn, "5G:") || len(snn) == len("5G:") {
+		return fmt.Errorf("invalid serving network name: %q", snn)
+	}
+	return nil
+}
+
+// Derive5G turns a UMTS quintet into a 5G HE AV for the serving network snn,
+// following TS 33.501 Annex A.2 (KAUSF), A.4 (XRES*), A.5 (HXRES*) and
+// A.6 (KSEAF). The quintet must have been generated with the AMF separation
+// bit set.
+func Derive5G(vec *AuthVector, snn string) (*Vector5G, error) {
+	if err := ValidateServingNetworkName(snn); err != nil {
+		return nil, err
+	}
+	q, err := decodeQuintet(vec)
+	if err != nil {
+		return nil, err
+	}
+
+	key := append(append([]byte{}, q.ck...), q.ik...)
+	sqnXorAk := q.autn[:6]
+
+	xresStar := kdf(key, fcRESStar, []byte(snn), q.rand, q.res)[16:]
+	kausf := kdf(key, fcKAUSF, []byte(snn), sqnXorAk)
+	kseaf := kdf(kausf, fcKSEAF, []byte(snn))
+
+	return &Vector5G{
+		Rand:      vec.Rand,
+		Autn:      vec.Autn,
+		XresStar:  hex.EncodeToString(xresStar),
+		HxresStar: hex.EncodeToString(hashRES(q.rand, xresStar)),
+		Kausf:     hex.EncodeToString(kausf),
+		Kseaf:     hex.EncodeToString(kseaf),
+	}, nil
+}
+
+// HashRES returns the lower 128 bits of SHA-256(RAND || RES), the HXRES*
+// construction of TS 33.501 Annex A.5. Applied to a plain XRES it gives the
+// HXRES returned by the confirmation flow.
+func HashRES(randHex, resHex string) (string, error) {
+	rand, err := hex.DecodeString(randHex)
+	if err != nil {
+		return "", fmt.Errorf("invalid RAND: %w", err)
+	}
+	res, err := hex.DecodeString(resHex)
+	if err != nil {
+		return "", fmt.Errorf("invalid RES: %w", err)
+	}
+	return hex.EncodeToString(hashRES(rand, res)), nil
+}
+
+func hashRES(rand, res []byte) []byte {
+	h := sha256.Sum256(append(append([]byte{}, rand...), res...))
+	return h[16:]
+}
@@ -0,0 +1,72 @@
+package aka
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// KDF FC value for Ks_NAF derivation (TS 33.220 Annex B.3).
+const fcKsNAF = 0x01
+
+// gbaMELabel is the static string of the GBA_ME Ks_(ext)_NAF derivation.
+const gbaMELabel = "gba-me"
+
+// GBAVector is the result of a GBA bootstrapping run (TS 33.220 4.5.2).
+// Ks = CK || IK never leaves the BSF, so it is not serialized.
+type GBAVector struct {
+	BTID string `json:"b_tid"`
+	Rand string `json:"rand"`
+	Autn string `json:"autn"`
+	Xres string `json:"xres"`
+	Ks   string `json:"-"`
+}
+
+// BSFName returns the BSF domain name of the home network (TS 23.003 16.2).
+func BSFName(mcc, mnc string) string {
+	if len(mnc) == 2 {
+		mnc = "0" + mnc
+	}
+	return fmt.Sprintf("bsf.mnc%s.mcc%s.pub.3gppnetwork.org", mnc, mcc)
+}
+
+// IMPI returns the private user identity derived from imsi (TS 23.003 13.3).
+func IMPI(imsi, mcc, mnc string) string {
+	if len(mnc) == 2 {
+		mnc = "0" + mnc
+	}
+	return fmt.Sprintf("%s@ims.mnc%s.mcc%s.3gppnetwork.org", imsi, mnc, mcc)
+}
+
+// DeriveGBA turns a quintet into a bootstrapping result with Ks = CK || IK
+// and B-TID = base64(RAND) "@" bsfName.
+func DeriveGBA(vec *AuthVector, bsfName string) (*GBAVector, error) {
+	if bsfName == "" {
+		return nil, fmt.Errorf("BSF name is required")
+	}
+	q, err := decodeQuintet(vec)
+	if err != nil {
+		return nil, err
+	}
+	return &GBAVector{
+		BTID: base64.StdEncoding.EncodeToString(q.rand) + "@" + bsfName,
+		Rand: vec.Rand,
+		Autn: vec.Autn,
+		Xres: vec.Xres,
+		Ks:   vec.Ck + vec.Ik,
+	}, nil
+}
+
+// DeriveKsNAF derives Ks_(ext)_NAF = KDF(Ks, "gba-me", RAND, IMPI, NAF_Id)
+// (TS 33.220 Annex B.3).
+func DeriveKsNAF(ksHex, randHex, impi string, nafID []byte) (string, error) {
+	ks, err := hex.DecodeString(ksHex)
+	if err != nil || len(ks) != 32 {
+		return "", fmt.Errorf("invalid Ks")
+	}
+	rand, err := hex.DecodeString(randHex)
+	if err != nil || len(rand) != 16 {
+		return "", fmt.Errorf("invalid RAND")
+	}
+	return hex.EncodeToString(kdf(ks, fcKsNAF, []byte(gbaMELabel), rand, []byte(impi), nafID)), nil
+}
@@ -0,0 +1,29 @@
+package aka
+
+import "testing"
+
+func TestDeriveGBA(t *testing.T) {
+	g, err := DeriveGBA(testSet1Vector, BSFName("001", "01"))
+	if err != nil {
+		t.Fatalf("DeriveGBA failed: %v", err)
+	}
+	if g.BTID != "I1U8vpY3qJ0hiuZNrke/NQ==@bsf.mnc001.mcc001.pub.3gppnetwork.org" {
+		t.Errorf("Unexpected B-TID %s", g.BTID)
+	}
+	if g.Ks != testSet1Vector.Ck+testSet1Vector.Ik {
+		t.Errorf("Ks must be CK || IK, got %s", g.Ks)
+	}
+
+	impi := IMPI("001010123456789", "001", "01")
+	if impi != "001010123456789@ims.mnc001.mcc001.3gppnetwork.org" {
+		t.Errorf("Unexpected IMPI %s", impi)
+	}
+	nafID, _ := ApplicationID("naf.example.com", "0100000002")
+	ksNAF, err := DeriveKsNAF(g.Ks, g.Rand, impi, nafID)
+	if err != nil {
+		t.Fatalf("DeriveKsNAF failed: %v", err)
+	}
+	if ksNAF != "ae9ecc3c7c17692c1d44d6d68d53a3b1624a706039753ba991e8293f3cfdec08" {
+		t.Errorf("Unexpected Ks_NAF %s", ksNAF)
+	}
+}
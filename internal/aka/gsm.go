@@ -0,0 +1,51 @@
+package aka
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// Triplet is a GSM authentication triplet as used by EAP-SIM (RFC 4186).
+type Triplet struct {
+	Rand string `json:"rand"`
+	Sres string `json:"sres"`
+	Kc   string `json:"kc"`
+}
+
+// DeriveTriplet converts a UMTS quintet into a GSM triplet using the
+// conversion functions c2 and c3 of TS 33.102 6.8.1.2. RAND is passed
+// through unchanged.
+func DeriveTriplet(vec *AuthVector) (*Triplet, error) {
+	q, err := decodeQuintet(vec)
+	if err != nil {
+		return nil, err
+	}
+	if len(q.res) < 4 || len(q.res) > 16 || len(q.res)%4 != 0 {
+		return nil, fmt.Errorf("invalid XRES length: %d", len(q.res))
+	}
+	return &Triplet{
+		Rand: vec.Rand,
+		Sres: hex.EncodeToString(c2(q.res)),
+		Kc:   hex.EncodeToString(c3(q.ck, q.ik)),
+	}, nil
+}
+
+// c2 folds RES into the 32-bit SRES by XOR-ing its 32-bit words. Shorter RES
+// values are treated as zero-padded to 128 bits, so missing words drop out.
+func c2(res []byte) []byte {
+	sres := make([]byte, 4)
+	for i, b := range res {
+		sres[i%4] ^= b
+	}
+	return sres
+}
+
+// c3 derives the 64-bit Kc as CK1 ^ CK2 ^ IK1 ^ IK2, where CK1/IK1 and
+// CK2/IK2 are the upper and lower halves of CK and IK.
+func c3(ck, ik []byte) []byte {
+	kc := make([]byte, 8)
+	for i := range kc {
+		kc[i] = ck[i] ^ ck[i+8] ^ ik[i] ^ ik[i+8]
+	}
+	return kc
+}
@@ -0,0 +1,28 @@
+package aka
+
+import "testing"
+
+func TestDeriveTriplet(t *testing.T) {
+	// TS 35.208 test set 1 quintet; the expected SRES and Kc are the c2/c3
+	// outputs that TS 35.208 lists for test set 1.
+	tr, err := DeriveTriplet(testSet1Vector)
+	if err != nil {
+		t.Fatalf("DeriveTriplet failed: %v", err)
+	}
+	if tr.Rand != testSet1Vector.Rand {
+		t.Errorf("RAND must be passed through unchanged")
+	}
+	if tr.Sres != "46f8416a" {
+		t.Errorf("Unexpected SRES %s", tr.Sres)
+	}
+	if tr.Kc != "eae4be823af9a08b" {
+		t.Errorf("Unexpected Kc %s", tr.Kc)
+	}
+}
+
+func TestC2ShortRES(t *testing.T) {
+	// A 32-bit RES is its own SRES.
+	if got := c2([]byte{0xde, 0xad, 0xbe, 0xef}); string(got) != "\xde\xad\xbe\xef" {
+		t.Errorf("Unexpected SRES %x", got)
+	}
+}
@@ -2,99 +2,176 @@ package aka
 
 import (
 	"bytes"
-	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"math/rand"
 	"time"
 
 	"aka-server/internal/model"
-
-	"github.com/wmnsk/milenage"
 )
 
+// ErrMACS is returned by Resync when the MAC-S in AUTS does not verify, i.e.
+// the AUTS was not produced by the subscriber's USIM for this RAND.
+var ErrMACS = errors.New("MAC-S verification failed")
+
 type AuthVector struct {
 	Rand string `json:"rand"`
 	Autn string `json:"autn"`
 	Xres string `json:"xres"`
 	Ck   string `json:"ck"`
 	Ik   string `json:"ik"`
+	// SQN the vector was issued with. Not part of the response.
+	SQN string `json:"-"`
+}
+
+// Generator issues authentication vectors under an SQN management policy.
+type Generator struct {
+	SQN SQNConfig
+	// Clock drives time-based SQNs. Tests may replace it.
+	Clock func() time.Time
+	// Rand supplies RAND. Tests may replace it with a deterministic source.
+	Rand RandSource
+}
+
+// NewGenerator returns a Generator using the given SQN configuration, the
+// system clock and CryptoRandSource.
+func NewGenerator(sqn SQNConfig) (*Generator, error) {
+	if err := sqn.Validate(); err != nil {
+		return nil, err
+	}
+	return &Generator{SQN: sqn, Clock: time.Now, Rand: CryptoRandSource}, nil
+}
+
+// defaultGenerator backs the package-level functions.
+var defaultGenerator = &Generator{SQN: DefaultSQNConfig, Clock: time.Now, Rand: CryptoRandSource}
+
+// seqFloor returns the lowest SEQ the subscriber's SQN profile allows now.
+func (g *Generator) seqFloor(sub *model.Subscriber) uint64 {
+	if sub.SQNProfile != SQNProfileTime {
+		return 0
+	}
+	return g.SQN.clockSEQ(g.Clock())
 }
 
-// GenerateVector generates an authentication vector for the given subscriber.
+// GenerateVector generates an authentication vector for the given subscriber
+// with DefaultSQNConfig.
 // It returns the vector and the new SQN (hex string) to be updated in the DB.
 func GenerateVector(sub *model.Subscriber) (*AuthVector, string, error) {
-	ki, err := hex.DecodeString(sub.Ki)
+	return defaultGenerator.GenerateVector(sub, "")
+}
+
+// GenerateVectors generates n vectors with DefaultSQNConfig.
+// See Generator.GenerateVectors.
+func GenerateVectors(sub *model.Subscriber, n int) ([]*AuthVector, string, error) {
+	return defaultGenerator.GenerateVectors(sub, n, "")
+}
+
+// Resync runs the resynchronization procedure with DefaultSQNConfig.
+// See Generator.Resync.
+func Resync(sub *model.Subscriber, randHex, autsHex string) (*AuthVector, string, error) {
+	return defaultGenerator.Resync(sub, randHex, autsHex, "")
+}
+
+// GenerateVector generates an authentication vector for the given subscriber
+// using the algorithm set selected by sub.Algorithm. node identifies the
+// requesting node for per-node IND allocation.
+// It returns the vector and the new SQN (hex string) to be updated in the DB.
+func (g *Generator) GenerateVector(sub *model.Subscriber, node string) (*AuthVector, string, error) {
+	sqn, err := decodeSQN(sub.SQN)
 	if err != nil {
-		return nil, "", fmt.Errorf("invalid Ki: %w", err)
+		return nil, "", err
+	}
+	if err := ValidateSQNProfile(sub.SQNProfile); err != nil {
+		return nil, "", err
 	}
-	opc, err := hex.DecodeString(sub.Opc)
+	newSqn, err := g.SQN.next(sqn, node, g.seqFloor(sub))
 	if err != nil {
-		return nil, "", fmt.Errorf("invalid OPC: %w", err)
+		return nil, "", err
 	}
-	sqnBytes, err := hex.DecodeString(sub.SQN)
+
+	vec, err := g.vector(sub, sqnToBytes(newSqn))
 	if err != nil {
-		return nil, "", fmt.Errorf("invalid SQN: %w", err)
+		return nil, "", err
+	}
+	return vec, hex.EncodeToString(sqnToBytes(newSqn)), nil
+}
+
+// GenerateVectors generates n authentication vectors with consecutive SEQ
+// values, starting after sub.SQN. It returns the vectors in issue order and
+// the SQN of the last one, which is the only SQN that needs to be stored.
+func (g *Generator) GenerateVectors(sub *model.Subscriber, n int, node string) ([]*AuthVector, string, error) {
+	cur := *sub
+	vecs := make([]*AuthVector, 0, n)
+	for i := 0; i < n; i++ {
+		vec, newSQN, err := g.GenerateVector(&cur, node)
+		if err != nil {
+			return nil, "", err
+		}
+		vecs = append(vecs, vec)
+		cur.SQN = newSQN
+	}
+	return vecs, cur.SQN, nil
+}
+
+// vector computes the quintet for the given SQN with a fresh RAND.
+func (g *Generator) vector(sub *model.Subscriber, sqnBytes []byte) (*AuthVector, error) {
+	alg, err := NewAlgorithmSet(sub)
+	if err != nil {
+		return nil, err
 	}
 	amfBytes, err := hex.DecodeString(sub.AMF)
 	if err != nil {
-		return nil, "", fmt.Errorf("invalid AMF: %w", err)
+		return nil, fmt.Errorf("invalid AMF: %w", err)
+	}
+	if len(amfBytes) != 2 {
+		return nil, fmt.Errorf("invalid AMF length")
 	}
 
 	// Generate RAND
 	randBytes := make([]byte, 16)
-	_, err = rand.Read(randBytes)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to generate RAND: %w", err)
+	if err := g.Rand.NewRand(randBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate RAND: %w", err)
 	}
 
-	// Increment SQN (SEQ only)
-	sqnVal := binary.BigEndian.Uint64(append([]byte{0, 0}, sqnBytes...))
-	ind := sqnVal & 0x1F
-	seq := sqnVal >> 5
-	seq++
-	newSqnVal := (seq << 5) | ind
-
-	newSqnBytes := make([]byte, 8)
-	binary.BigEndian.PutUint64(newSqnBytes, newSqnVal)
-	newSqnBytes = newSqnBytes[2:] // Take last 6 bytes
-
-	amfVal := binary.BigEndian.Uint16(amfBytes)
-
-	// Calculate Milenage
-	m := milenage.NewWithOPc(ki, opc, randBytes, newSqnVal, amfVal)
-
-	if err := m.ComputeAll(); err != nil {
-		return nil, "", fmt.Errorf("milenage computation failed: %w", err)
+	mac, err := alg.F1(randBytes, sqnBytes, amfBytes)
+	if err != nil {
+		return nil, fmt.Errorf("f1 computation failed: %w", err)
 	}
-
-	autn, err := m.GenerateAUTN()
+	res, ck, ik, ak, err := alg.F2345(randBytes)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to generate AUTN: %w", err)
+		return nil, fmt.Errorf("f2-f5 computation failed: %w", err)
 	}
 
-	vec := &AuthVector{
-		Rand: hex.EncodeToString(randBytes),
-		Autn: hex.EncodeToString(autn),
-		Xres: hex.EncodeToString(m.RES),
-		Ck:   hex.EncodeToString(m.CK),
-		Ik:   hex.EncodeToString(m.IK),
+	// AUTN = SQN ^ AK || AMF || MAC-A
+	autn := make([]byte, 0, 16)
+	for i := 0; i < 6; i++ {
+		autn = append(autn, sqnBytes[i]^ak[i])
 	}
+	autn = append(autn, amfBytes...)
+	autn = append(autn, mac...)
 
-	return vec, hex.EncodeToString(newSqnBytes), nil
+	return &AuthVector{
+		Rand: hex.EncodeToString(randBytes),
+		Autn: hex.EncodeToString(autn),
+		Xres: hex.EncodeToString(res),
+		Ck:   hex.EncodeToString(ck),
+		Ik:   hex.EncodeToString(ik),
+		SQN:  hex.EncodeToString(sqnBytes),
+	}, nil
 }
 
 // Resync handles the resynchronization procedure.
-// It verifies MAC-S in AUTS and recovers the SQN from the USIM.
-// Returns the new vector and the recovered SQN.
-func Resync(sub *model.Subscriber, randHex, autsHex string) (*AuthVector, string, error) {
-	ki, err := hex.DecodeString(sub.Ki)
+// It verifies MAC-S in AUTS, recovers SQN_MS from the USIM and checks it
+// against the HE's SQN (see SQNConfig.resyncBase).
+// Returns the new vector and the SQN to store.
+func (g *Generator) Resync(sub *model.Subscriber, randHex, autsHex, node string) (*AuthVector, string, error) {
+	alg, err := NewAlgorithmSet(sub)
 	if err != nil {
-		return nil, "", fmt.Errorf("invalid Ki: %w", err)
+		return nil, "", err
 	}
-	opc, err := hex.DecodeString(sub.Opc)
+	sqnHE, err := decodeSQN(sub.SQN)
 	if err != nil {
-		return nil, "", fmt.Errorf("invalid OPC: %w", err)
+		return nil, "", err
 	}
 	randBytes, err := hex.DecodeString(randHex)
 	if err != nil {
@@ -115,9 +192,8 @@ func Resync(sub *model.Subscriber, randHex, autsHex string) (*AuthVector, string
 	macS := autsBytes[6:]
 
 	// Calculate AK* (AKS)
-	// We use a dummy SQN/AMF for NewWithOPc because F5Star only depends on K, OPc, RAND
-	m := milenage.NewWithOPc(ki, opc, randBytes, 0, 0)
-	aks, err := m.F5Star()
+	// F5Star only depends on K, OPc, RAND
+	aks, err := alg.F5Star(randBytes)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to calculate AKS: %w", err)
 	}
@@ -130,31 +206,25 @@ func Resync(sub *model.Subscriber, randHex, autsHex string) (*AuthVector, string
 
 	// Verify MAC-S
 	// MAC-S = F1*(K, RAND, SQN_MS, AMF=0)
-	// We need to call F1Star.
-	// F1Star takes sqn []byte and amf []byte
 	amfStar := []byte{0, 0}
-	xmacS, err := m.F1Star(sqnMsBytes, amfStar)
+	xmacS, err := alg.F1Star(randBytes, sqnMsBytes, amfStar)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to calculate XMAC-S: %w", err)
 	}
 
 	if !bytes.Equal(macS, xmacS) {
-		return nil, "", fmt.Errorf("MAC-S verification failed")
+		return nil, "", ErrMACS
 	}
 
 	// Resync successful.
-	// Update SQN to SQN_MS.
+	// Continue from SQN_HE or SQN_MS, whichever the USIM will accept.
 	// Then generate new vector.
+	base, err := g.SQN.resyncBase(sqnHE, sqnFromBytes(sqnMsBytes), g.seqFloor(sub))
+	if err != nil {
+		return nil, "", err
+	}
 
-	return GenerateVector(&model.Subscriber{
-		IMSI: sub.IMSI,
-		Ki:   sub.Ki,
-		Opc:  sub.Opc,
-		SQN:  hex.EncodeToString(sqnMsBytes),
-		AMF:  sub.AMF,
-	})
-}
-
-func init() {
-	rand.Seed(time.Now().UnixNano())
+	resynced := *sub
+	resynced.SQN = hex.EncodeToString(sqnToBytes(base))
+	return g.GenerateVector(&resynced, node)
 }
@@ -38,6 +38,51 @@ func TestGenerateVector(t *testing.T) {
 	}
 }
 
+func TestGenerateVectors(t *testing.T) {
+	sub := &model.Subscriber{
+		IMSI: "123456789012345",
+		Ki:   "00112233445566778899aabbccddeeff",
+		Opc:  "000102030405060708090a0b0c0d0e0f",
+		SQN:  "000000000023", // SEQ=1, IND=3
+		AMF:  "8000",
+	}
+
+	vecs, lastSQN, err := GenerateVectors(sub, 3)
+	if err != nil {
+		t.Fatalf("GenerateVectors failed: %v", err)
+	}
+	if len(vecs) != 3 {
+		t.Fatalf("Expected 3 vectors, got %d", len(vecs))
+	}
+
+	// SEQ 2, 3, 4 with IND kept. SQN ^ AK is in AUTN, so recover it with AK.
+	ki, _ := hex.DecodeString(sub.Ki)
+	opc, _ := hex.DecodeString(sub.Opc)
+	want := []string{"000000000043", "000000000063", "000000000083"}
+	for i, vec := range vecs {
+		randBytes, _ := hex.DecodeString(vec.Rand)
+		autn, _ := hex.DecodeString(vec.Autn)
+		m := milenage.NewWithOPc(ki, opc, randBytes, 0, 0)
+		_, _, _, ak, err := m.F2345()
+		if err != nil {
+			t.Fatalf("F2345 failed: %v", err)
+		}
+		sqn := make([]byte, 6)
+		for j := range sqn {
+			sqn[j] = autn[j] ^ ak[j]
+		}
+		if hex.EncodeToString(sqn) != want[i] {
+			t.Errorf("Vector %d: expected SQN %s, got %x", i, want[i], sqn)
+		}
+	}
+	if lastSQN != "000000000083" {
+		t.Errorf("Expected last SQN 000000000083, got %s", lastSQN)
+	}
+	if sub.SQN != "000000000023" {
+		t.Errorf("Input subscriber modified: %s", sub.SQN)
+	}
+}
+
 func TestResync(t *testing.T) {
 	// Scenario: HE has SQN=10. USIM has SQN=20.
 	// HE sends vector with SQN=11.
@@ -0,0 +1,65 @@
+package aka
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"aka-server/internal/model"
+
+	"github.com/wmnsk/milenage"
+)
+
+// ComputeOPc derives OPc from Ki and the operator key OP (TS 35.206 4.1),
+// or TOPc from TOP when algorithm is AlgorithmTUAK (TS 35.231 6.1). All keys
+// are hex encoded.
+func ComputeOPc(algorithm, kiHex, opHex string) (string, error) {
+	if err := ValidateAlgorithm(algorithm); err != nil {
+		return "", err
+	}
+	ki, err := hex.DecodeString(kiHex)
+	if err != nil {
+		return "", fmt.Errorf("invalid Ki: %w", err)
+	}
+	op, err := hex.DecodeString(opHex)
+	if err != nil {
+		return "", fmt.Errorf("invalid OP: %w", err)
+	}
+	opc, err := computeOPc(algorithm, ki, op)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(opc), nil
+}
+
+func computeOPc(algorithm string, ki, op []byte) ([]byte, error) {
+	if algorithm == AlgorithmTUAK {
+		return TuakTOPc(ki, op, 1)
+	}
+	if len(op) != 16 {
+		return nil, fmt.Errorf("invalid OP length: %d", len(op))
+	}
+	if err := checkKeyLengths(algorithm, ki, nil); err != nil {
+		return nil, err
+	}
+	return milenage.ComputeOPc(ki, op)
+}
+
+// subscriberOPc returns the subscriber's OPc. A subscriber without one
+// takes it from the OP of its operator profile.
+func subscriberOPc(sub *model.Subscriber, ki []byte) ([]byte, error) {
+	if sub.Opc != "" || sub.Operator == nil || sub.Operator.OP == "" {
+		opc, err := hex.DecodeString(sub.Opc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OPC: %w", err)
+		}
+		if len(opc) == 0 {
+			return nil, fmt.Errorf("no OPC and no operator OP for subscriber")
+		}
+		return opc, nil
+	}
+	op, err := hex.DecodeString(sub.Operator.OP)
+	if err != nil {
+		return nil, fmt.Errorf("invalid operator OP: %w", err)
+	}
+	return computeOPc(sub.Algorithm, ki, op)
+}
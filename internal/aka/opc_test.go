@@ -0,0 +1,99 @@
+package aka
+
+import (
+	"bytes"
+	"testing"
+
+	"aka-server/internal/model"
+)
+
+func TestComputeOPc(t *testing.T) {
+	tests := []struct {
+		name, algorithm, k, op, want string
+	}{
+		// TS 35.208 test set 1.
+		{"milenage", AlgorithmMilenage, "465b5ce8b199b49faa5f0a2ee238a6bc", "cdc202d5123e20f62b6d676ac72cb318", "cd63cb71954a9f4e48a5994e37a02baf"},
+		{"default", "", "465b5ce8b199b49faa5f0a2ee238a6bc", "cdc202d5123e20f62b6d676ac72cb318", "cd63cb71954a9f4e48a5994e37a02baf"},
+		// TS 35.232 test set 1.
+		{"tuak", AlgorithmTUAK, tuakK, tuakTOP, tuakTOPc},
+	}
+	for _, tt := range tests {
+		got, err := ComputeOPc(tt.algorithm, tt.k, tt.op)
+		if err != nil {
+			t.Fatalf("%s: ComputeOPc failed: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := ComputeOPc(AlgorithmMilenage, "465b5ce8b199b49faa5f0a2ee238a6bc", "cdc202"); err == nil {
+		t.Error("Expected error for short OP")
+	}
+}
+
+func TestOperatorOPDerivesOPc(t *testing.T) {
+	rand := mustHex(t, "23553cbe9637a89d218ae64dae47bf35")
+	withOPc := &model.Subscriber{Ki: "465b5ce8b199b49faa5f0a2ee238a6bc", Opc: "cd63cb71954a9f4e48a5994e37a02baf"}
+	withOP := &model.Subscriber{
+		Ki:       "465b5ce8b199b49faa5f0a2ee238a6bc",
+		Operator: &model.OperatorProfile{OP: "cdc202d5123e20f62b6d676ac72cb318"},
+	}
+
+	want, err := NewAlgorithmSet(withOPc)
+	if err != nil {
+		t.Fatalf("NewAlgorithmSet failed: %v", err)
+	}
+	got, err := NewAlgorithmSet(withOP)
+	if err != nil {
+		t.Fatalf("NewAlgorithmSet failed: %v", err)
+	}
+	wRes, _, _, _, _ := want.F2345(rand)
+	gRes, _, _, _, _ := got.F2345(rand)
+	if !bytes.Equal(gRes, wRes) {
+		t.Errorf("RES with operator OP = %x, want %x", gRes, wRes)
+	}
+
+	// A stored OPc takes precedence over the operator OP.
+	withOP.Opc = "000102030405060708090a0b0c0d0e0f"
+	other, _ := NewAlgorithmSet(withOP)
+	if oRes, _, _, _, _ := other.F2345(rand); bytes.Equal(oRes, wRes) {
+		t.Error("Expected the stored OPc to be used")
+	}
+
+	if _, err := NewAlgorithmSet(&model.Subscriber{Ki: withOPc.Ki}); err == nil {
+		t.Error("Expected error without OPc or operator OP")
+	}
+}
+
+func TestKeyLengthsPerAlgorithm(t *testing.T) {
+	k16, k32 := "465b5ce8b199b49faa5f0a2ee238a6bc", tuakK+tuakK
+	opc16 := "cd63cb71954a9f4e48a5994e37a02baf"
+	tests := []struct {
+		algorithm, ki, opc string
+		ok                 bool
+	}{
+		{AlgorithmMilenage, k16, opc16, true},
+		{"", k16, opc16, true},
+		{AlgorithmMilenage, k32, opc16, false},
+		{AlgorithmMilenage, k16, tuakTOPc, false},
+		{AlgorithmTUAK, k16, tuakTOPc, true},
+		{AlgorithmTUAK, k32, tuakTOPc, true},
+		{AlgorithmTUAK, k32, opc16, false},
+		{AlgorithmTUAK, k32 + "00", tuakTOPc, false},
+	}
+	for _, tt := range tests {
+		err := ValidateKeyLengths(tt.algorithm, tt.ki, tt.opc)
+		if (err == nil) != tt.ok {
+			t.Errorf("ValidateKeyLengths(%q, %d hex, %d hex) = %v", tt.algorithm, len(tt.ki), len(tt.opc), err)
+		}
+		_, err = NewAlgorithmSet(&model.Subscriber{Algorithm: tt.algorithm, Ki: tt.ki, Opc: tt.opc})
+		if (err == nil) != tt.ok {
+			t.Errorf("NewAlgorithmSet(%q, %d hex, %d hex) = %v", tt.algorithm, len(tt.ki), len(tt.opc), err)
+		}
+	}
+
+	if _, err := ComputeOPc(AlgorithmMilenage, k32, "cdc202d5123e20f62b6d676ac72cb318"); err == nil {
+		t.Error("Expected error for a 32-byte Milenage Ki")
+	}
+}
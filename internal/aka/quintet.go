@@ -0,0 +1,67 @@
+package aka
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"aka-server/internal/model"
+)
+
+// quintet holds the decoded binary form of an AuthVector.
+type quintet struct {
+	rand, autn, res, ck, ik []byte
+}
+
+func decodeQuintet(vec *AuthVector) (*quintet, error) {
+	var q quintet
+	fields := []struct {
+		name string
+		hex  string
+		dst  *[]byte
+		size int
+	}{
+		{"RAND", vec.Rand, &q.rand, 16},
+		{"AUTN", vec.Autn, &q.autn, 16},
+		{"XRES", vec.Xres, &q.res, 0},
+		{"CK", vec.Ck, &q.ck, 16},
+		{"IK", vec.Ik, &q.ik, 16},
+	}
+	for _, f := range fields {
+		b, err := hex.DecodeString(f.hex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", f.name, err)
+		}
+		if f.size != 0 && len(b) != f.size {
+			return nil, fmt.Errorf("invalid %s length", f.name)
+		}
+		*f.dst = b
+	}
+	return &q, nil
+}
+
+// WithSeparationBit returns a copy of sub whose AMF has the separation bit
+// (bit 0, the most significant bit, see TS 33.401 Annex H) set to 1. EPS and
+// 5G vectors must carry this bit; the stored AMF is left untouched.
+func WithSeparationBit(sub *model.Subscriber) (*model.Subscriber, error) {
+	amf, err := hex.DecodeString(sub.AMF)
+	if err != nil || len(amf) != 2 {
+		return nil, fmt.Errorf("invalid AMF: %q", sub.AMF)
+	}
+	amf[0] |= 0x80
+	cp := *sub
+	cp.AMF = hex.EncodeToString(amf)
+	return &cp, nil
+}
+
+// IsDigits reports whether s is a string of minLen to maxLen decimal digits.
+func IsDigits(s string, minLen, maxLen int) bool {
+	if len(s) < minLen || len(s) > maxLen {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package aka
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// RandSource supplies the RAND challenges of new vectors.
+type RandSource interface {
+	// NewRand fills b with a fresh RAND.
+	NewRand(b []byte) error
+}
+
+// CryptoRandSource draws RAND from crypto/rand. It is the source used in
+// production.
+var CryptoRandSource RandSource = readerSource{rand.Reader}
+
+type readerSource struct{ r io.Reader }
+
+func (s readerSource) NewRand(b []byte) error {
+	_, err := io.ReadFull(s.r, b)
+	return err
+}
+
+// deterministicSource produces SHA-256(seed || counter) blocks.
+type deterministicSource struct {
+	mu      sync.Mutex
+	seed    []byte
+	counter uint64
+}
+
+// NewDeterministicRandSource returns a RandSource whose output depends only
+// on seed, so the same seed reproduces the same vectors. It must not be used
+// in production.
+func NewDeterministicRandSource(seed []byte) RandSource {
+	return &deterministicSource{seed: append([]byte(nil), seed...)}
+}
+
+func (s *deterministicSource) NewRand(b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(b) > 0 {
+		var ctr [8]byte
+		binary.BigEndian.PutUint64(ctr[:], s.counter)
+		s.counter++
+		h := sha256.Sum256(append(s.seed[:len(s.seed):len(s.seed)], ctr[:]...))
+		b = b[copy(b, h[:]):]
+	}
+	return nil
+}
+
+// fixedSource returns a preset list of RANDs in order.
+type fixedSource struct {
+	mu    sync.Mutex
+	rands [][]byte
+}
+
+// NewFixedRandSource returns a RandSource that yields rands in order and
+// fails once they are used up. It is meant for known-answer tests.
+func NewFixedRandSource(rands ...[]byte) RandSource {
+	return &fixedSource{rands: rands}
+}
+
+func (s *fixedSource) NewRand(b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.rands) == 0 {
+		return fmt.Errorf("fixed RAND source exhausted")
+	}
+	if len(s.rands[0]) != len(b) {
+		return fmt.Errorf("fixed RAND has length %d, want %d", len(s.rands[0]), len(b))
+	}
+	copy(b, s.rands[0])
+	s.rands = s.rands[1:]
+	return nil
+}
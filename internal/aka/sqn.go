@@ -0,0 +1,204 @@
+package aka
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+)
+
+// SQN profiles accepted in model.Subscriber.SQNProfile. An empty value
+// means SQNProfileCounter.
+const (
+	// SQNProfileCounter is Profile 2 of TS 33.102 Annex C.3.2: SEQ is a
+	// per-subscriber counter.
+	SQNProfileCounter = "counter"
+	// SQNProfileTime is Profile 3 of Annex C.3.3: SEQ follows a clock with
+	// SQNConfig.TimeGranularity ticks, and only steps beyond it when several
+	// vectors are issued within one tick.
+	SQNProfileTime = "time"
+)
+
+// ValidateSQNProfile checks that name is a supported SQN profile.
+func ValidateSQNProfile(name string) error {
+	switch name {
+	case "", SQNProfileCounter, SQNProfileTime:
+		return nil
+	}
+	return fmt.Errorf("unsupported SQN profile: %q", name)
+}
+
+// IND allocation policies for SQNConfig.INDAllocation.
+const (
+	// INDFixed keeps the IND of the stored SQN and only increments SEQ.
+	INDFixed = "fixed"
+	// INDPerVector cycles IND through the whole IND array, one step per
+	// vector (TS 33.102 C.3.2).
+	INDPerVector = "vector"
+	// INDPerNode derives IND from the requesting node, so that each VLR/SGSN
+	// or front-end consumes its own slot of the USIM's IND array.
+	INDPerNode = "node"
+)
+
+// ErrSEQExhausted is returned when issuing another vector would take SEQ
+// past the configured wrap-around limit. The subscriber needs a new SQN
+// range (e.g. USIM re-personalisation) before it can be served again.
+var ErrSEQExhausted = errors.New("SEQ exhausted: sequence number would wrap around")
+
+// ErrResyncTooFarAhead is returned when a verified AUTS carries a SEQ_MS
+// more than ResyncMaxAhead ahead of the HE's SEQ.
+var ErrResyncTooFarAhead = errors.New("SQN_MS too far ahead of SQN_HE")
+
+// SQNConfig holds the SQN management parameters of TS 33.102 Annex C,
+// Profile 2. SQN = SEQ || IND, with IND taking the low INDBits bits.
+type SQNConfig struct {
+	INDBits       uint   // length of IND in bits, 5 in Profile 2
+	INDAllocation string // INDFixed, INDPerVector or INDPerNode
+	// Delta is the largest SEQ step the USIM accepts (Annex C.2.2). During
+	// resync the HE keeps its own SEQ only if it is within Delta of SEQ_MS.
+	Delta uint64
+	// ResyncMaxAhead rejects AUTS whose SEQ_MS is more than this far ahead of
+	// the HE's SEQ, as no genuine USIM can have accepted such a value.
+	ResyncMaxAhead uint64
+	// WrapMargin refuses new vectors once SEQ is within this distance of the
+	// largest representable SEQ (2^43-1 for a 5-bit IND).
+	WrapMargin uint64
+	// TimeGranularity is the length of one SEQ tick for SQNProfileTime.
+	TimeGranularity time.Duration
+}
+
+// DefaultSQNConfig is the Profile 2 configuration with the historical IND
+// handling (IND is kept, only SEQ is incremented).
+var DefaultSQNConfig = SQNConfig{
+	INDBits:         5,
+	INDAllocation:   INDFixed,
+	Delta:           1 << 28,
+	ResyncMaxAhead:  1 << 28,
+	WrapMargin:      0,
+	TimeGranularity: time.Second,
+}
+
+// Validate checks the configuration for consistency.
+func (c *SQNConfig) Validate() error {
+	if c.INDBits < 1 || c.INDBits > 16 {
+		return fmt.Errorf("invalid IND length: %d", c.INDBits)
+	}
+	switch c.INDAllocation {
+	case INDFixed, INDPerVector, INDPerNode:
+	default:
+		return fmt.Errorf("invalid IND allocation: %q", c.INDAllocation)
+	}
+	if c.Delta == 0 || c.ResyncMaxAhead == 0 {
+		return fmt.Errorf("delta and resync limit must be positive")
+	}
+	if c.WrapMargin >= c.maxSEQ() {
+		return fmt.Errorf("wrap margin too large: %d", c.WrapMargin)
+	}
+	if c.TimeGranularity <= 0 {
+		return fmt.Errorf("invalid time granularity: %s", c.TimeGranularity)
+	}
+	// With too fine a granularity the clock SEQ is already past the wrap
+	// limit and every time-based subscriber would be exhausted.
+	if seq := c.clockSEQ(time.Now()); seq >= c.maxSEQ()-c.WrapMargin {
+		return fmt.Errorf("time granularity too small: clock SEQ %d exceeds the limit %d", seq, c.maxSEQ()-c.WrapMargin)
+	}
+	return nil
+}
+
+func (c *SQNConfig) indMask() uint64 {
+	return 1<<c.INDBits - 1
+}
+
+func (c *SQNConfig) maxSEQ() uint64 {
+	return 1<<(48-c.INDBits) - 1
+}
+
+func (c *SQNConfig) split(sqn uint64) (seq, ind uint64) {
+	return sqn >> c.INDBits, sqn & c.indMask()
+}
+
+// clockSEQ returns the time-based SEQ for now: the number of
+// TimeGranularity ticks since the Unix epoch.
+func (c *SQNConfig) clockSEQ(now time.Time) uint64 {
+	return uint64(now.UnixNano() / int64(c.TimeGranularity))
+}
+
+// next returns the SQN following sqn: SEQ is incremented, raised to at least
+// floor, and IND allocated according to the policy. floor is the clock SEQ
+// for time-based subscribers and 0 otherwise. node identifies the requesting
+// node for INDPerNode.
+func (c *SQNConfig) next(sqn uint64, node string, floor uint64) (uint64, error) {
+	seq, ind := c.split(sqn)
+	seq = max(seq+1, floor)
+	if seq > c.maxSEQ()-c.WrapMargin {
+		return 0, ErrSEQExhausted
+	}
+
+	switch c.INDAllocation {
+	case INDPerVector:
+		ind = (ind + 1) & c.indMask()
+	case INDPerNode:
+		h := fnv.New32a()
+		h.Write([]byte(node))
+		ind = uint64(h.Sum32()) & c.indMask()
+	}
+	return seq<<c.INDBits | ind, nil
+}
+
+// resyncBase decides which SQN new vectors continue from after a verified
+// AUTS carrying sqnMS, following TS 33.102 6.3.5 and Annex C.3.4:
+//   - SEQ_MS implausibly far ahead of SEQ_HE (or of the clock SEQ floor for
+//     time-based subscribers) is rejected;
+//   - if SEQ_MS is ahead, SEQ_HE catches up to it;
+//   - if SEQ_HE is ahead by no more than Delta the USIM will accept it, so
+//     SEQ_HE is kept; further ahead it is reset to SEQ_MS.
+func (c *SQNConfig) resyncBase(sqnHE, sqnMS, floor uint64) (uint64, error) {
+	seqHE, _ := c.split(sqnHE)
+	seqMS, _ := c.split(sqnMS)
+
+	if ref := max(seqHE, floor); seqMS > ref && seqMS-ref > c.ResyncMaxAhead {
+		return 0, fmt.Errorf("%w (%d > %d)", ErrResyncTooFarAhead, seqMS-ref, c.ResyncMaxAhead)
+	}
+
+	switch {
+	case seqMS > seqHE:
+		return sqnMS, nil
+	case seqHE-seqMS < c.Delta:
+		return sqnHE, nil
+	default:
+		return sqnMS, nil
+	}
+}
+
+func sqnFromBytes(b []byte) uint64 {
+	return binary.BigEndian.Uint64(append([]byte{0, 0}, b...))
+}
+
+func sqnToBytes(sqn uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, sqn)
+	return b[2:] // Take last 6 bytes
+}
+
+func decodeSQN(s string) (uint64, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid SQN: %w", err)
+	}
+	if len(b) != 6 {
+		return 0, fmt.Errorf("invalid SQN length")
+	}
+	return sqnFromBytes(b), nil
+}
+
+// IND returns the IND part of a hex SQN under this configuration.
+func (c *SQNConfig) IND(sqnHex string) (uint64, error) {
+	sqn, err := decodeSQN(sqnHex)
+	if err != nil {
+		return 0, err
+	}
+	_, ind := c.split(sqn)
+	return ind, nil
+}
@@ -0,0 +1,252 @@
+package aka
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"aka-server/internal/model"
+
+	"github.com/wmnsk/milenage"
+)
+
+func TestSQNNextINDAllocation(t *testing.T) {
+	cfg := DefaultSQNConfig
+
+	// Fixed: SEQ 1 -> 2, IND 3 kept.
+	got, err := cfg.next(1<<5|3, "", 0)
+	if err != nil || got != 2<<5|3 {
+		t.Errorf("fixed: got %#x, %v", got, err)
+	}
+
+	// Per vector: IND cycles and wraps at 2^INDBits.
+	cfg.INDAllocation = INDPerVector
+	got, err = cfg.next(1<<5|3, "", 0)
+	if err != nil || got != 2<<5|4 {
+		t.Errorf("vector: got %#x, %v", got, err)
+	}
+	got, err = cfg.next(1<<5|31, "", 0)
+	if err != nil || got != 2<<5|0 {
+		t.Errorf("vector wrap: got %#x, %v", got, err)
+	}
+
+	// Per node: the same node always gets the same IND.
+	cfg.INDAllocation = INDPerNode
+	a1, _ := cfg.next(1<<5, "mme-a", 0)
+	a2, _ := cfg.next(7<<5, "mme-a", 0)
+	if a1&0x1f != a2&0x1f {
+		t.Errorf("node: IND differs for the same node: %#x vs %#x", a1, a2)
+	}
+	if a1>>5 != 2 || a2>>5 != 8 {
+		t.Errorf("node: SEQ not incremented: %#x %#x", a1, a2)
+	}
+}
+
+func TestSQNNextOverflow(t *testing.T) {
+	cfg := DefaultSQNConfig
+	maxSEQ := uint64(1)<<43 - 1
+
+	if _, err := cfg.next((maxSEQ-1)<<5, "", 0); err != nil {
+		t.Errorf("Last SEQ must still be issued: %v", err)
+	}
+	if _, err := cfg.next(maxSEQ<<5, "", 0); !errors.Is(err, ErrSEQExhausted) {
+		t.Errorf("Expected ErrSEQExhausted, got %v", err)
+	}
+
+	cfg.WrapMargin = 1000
+	if _, err := cfg.next((maxSEQ-1000)<<5, "", 0); !errors.Is(err, ErrSEQExhausted) {
+		t.Errorf("Expected ErrSEQExhausted inside wrap margin, got %v", err)
+	}
+}
+
+func TestSQNResyncBase(t *testing.T) {
+	cfg := DefaultSQNConfig
+	cfg.Delta = 100
+	cfg.ResyncMaxAhead = 1000
+
+	cases := []struct {
+		name       string
+		seqHE      uint64
+		seqMS      uint64
+		want       uint64
+		shouldFail bool
+	}{
+		{"MS ahead", 10, 20, 20, false},
+		{"MS at limit", 10, 1010, 1010, false},
+		{"MS implausibly far ahead", 10, 1011, 0, true},
+		{"HE ahead within delta", 50, 10, 50, false},
+		{"HE ahead beyond delta", 200, 10, 10, false},
+	}
+	for _, c := range cases {
+		got, err := cfg.resyncBase(c.seqHE<<5, c.seqMS<<5, 0)
+		if c.shouldFail {
+			if err == nil {
+				t.Errorf("%s: expected error", c.name)
+			}
+			continue
+		}
+		if err != nil || got != c.want<<5 {
+			t.Errorf("%s: got SEQ %d, %v; want %d", c.name, got>>5, err, c.want)
+		}
+	}
+}
+
+func TestGeneratorResyncRejectsFarAhead(t *testing.T) {
+	cfg := DefaultSQNConfig
+	cfg.ResyncMaxAhead = 100
+	gen, err := NewGenerator(cfg)
+	if err != nil {
+		t.Fatalf("NewGenerator failed: %v", err)
+	}
+
+	kiHex := "00112233445566778899aabbccddeeff"
+	opcHex := "000102030405060708090a0b0c0d0e0f"
+	ki, _ := hex.DecodeString(kiHex)
+	opc, _ := hex.DecodeString(opcHex)
+	randBytes := make([]byte, 16)
+
+	// HE at SEQ=10, USIM claims SEQ_MS=1000.
+	sub := &model.Subscriber{Ki: kiHex, Opc: opcHex, SQN: "000000000140", AMF: "8000"}
+	m := milenage.NewWithOPc(ki, opc, randBytes, 1000<<5, 0)
+	auts, err := m.GenerateAUTS()
+	if err != nil {
+		t.Fatalf("GenerateAUTS failed: %v", err)
+	}
+
+	if _, _, err := gen.Resync(sub, hex.EncodeToString(randBytes), hex.EncodeToString(auts), ""); err == nil {
+		t.Error("Expected resync to be rejected for SQN_MS far ahead")
+	}
+}
+
+func TestSQNConfigValidate(t *testing.T) {
+	if err := DefaultSQNConfig.Validate(); err != nil {
+		t.Errorf("Default config invalid: %v", err)
+	}
+	bad := DefaultSQNConfig
+	bad.INDAllocation = "random"
+	if err := bad.Validate(); err == nil {
+		t.Error("Expected error for unknown IND allocation")
+	}
+	bad = DefaultSQNConfig
+	bad.TimeGranularity = time.Microsecond
+	if err := bad.Validate(); err == nil {
+		t.Error("Expected error for a clock SEQ past the wrap limit")
+	}
+}
+
+// fakeClock is an injectable Generator.Clock.
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func seqOf(t *testing.T, sqnHex string) uint64 {
+	t.Helper()
+	sqn, err := decodeSQN(sqnHex)
+	if err != nil {
+		t.Fatalf("decodeSQN failed: %v", err)
+	}
+	return sqn >> 5
+}
+
+func TestGenerateVectorTimeBased(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	cfg := DefaultSQNConfig
+	cfg.TimeGranularity = 100 * time.Millisecond
+	gen, err := NewGenerator(cfg)
+	if err != nil {
+		t.Fatalf("NewGenerator failed: %v", err)
+	}
+	gen.Clock = clock.Now
+
+	sub := &model.Subscriber{
+		Ki:         "00112233445566778899aabbccddeeff",
+		Opc:        "000102030405060708090a0b0c0d0e0f",
+		SQN:        "000000000020",
+		AMF:        "8000",
+		SQNProfile: SQNProfileTime,
+	}
+	tick := uint64(17000000000) // 1700000000s in 100ms ticks
+
+	// SEQ jumps to the clock, not to stored SEQ + 1.
+	_, sqn1, err := gen.GenerateVector(sub, "")
+	if err != nil {
+		t.Fatalf("GenerateVector failed: %v", err)
+	}
+	if got := seqOf(t, sqn1); got != tick {
+		t.Errorf("Expected SEQ %d, got %d", tick, got)
+	}
+
+	// A second vector within the same tick still gets a fresh SEQ.
+	sub.SQN = sqn1
+	_, sqn2, _ := gen.GenerateVector(sub, "")
+	if got := seqOf(t, sqn2); got != tick+1 {
+		t.Errorf("Expected SEQ %d within the same tick, got %d", tick+1, got)
+	}
+
+	// Once the clock moves on, SEQ follows it again.
+	clock.now = clock.now.Add(time.Second)
+	sub.SQN = sqn2
+	_, sqn3, _ := gen.GenerateVector(sub, "")
+	if got := seqOf(t, sqn3); got != tick+10 {
+		t.Errorf("Expected SEQ %d after 1s, got %d", tick+10, got)
+	}
+
+	// Counter-based subscribers ignore the clock.
+	sub.SQNProfile = SQNProfileCounter
+	sub.SQN = "000000000020"
+	_, sqn4, _ := gen.GenerateVector(sub, "")
+	if sqn4 != "000000000040" {
+		t.Errorf("Expected counter SQN 000000000040, got %s", sqn4)
+	}
+}
+
+func TestResyncTimeBased(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	cfg := DefaultSQNConfig
+	cfg.ResyncMaxAhead = 100
+	gen, err := NewGenerator(cfg)
+	if err != nil {
+		t.Fatalf("NewGenerator failed: %v", err)
+	}
+	gen.Clock = clock.Now
+
+	kiHex := "00112233445566778899aabbccddeeff"
+	opcHex := "000102030405060708090a0b0c0d0e0f"
+	ki, _ := hex.DecodeString(kiHex)
+	opc, _ := hex.DecodeString(opcHex)
+	randBytes := make([]byte, 16)
+	sub := &model.Subscriber{Ki: kiHex, Opc: opcHex, SQN: "000000000020", AMF: "8000", SQNProfile: SQNProfileTime}
+
+	auts := func(seqMS uint64) string {
+		m := milenage.NewWithOPc(ki, opc, randBytes, seqMS<<5, 0)
+		b, err := m.GenerateAUTS()
+		if err != nil {
+			t.Fatalf("GenerateAUTS failed: %v", err)
+		}
+		return hex.EncodeToString(b)
+	}
+
+	// USIM slightly ahead of the clock (e.g. clock skew): continue after SQN_MS.
+	_, newSQN, err := gen.Resync(sub, hex.EncodeToString(randBytes), auts(1050), "")
+	if err != nil {
+		t.Fatalf("Resync failed: %v", err)
+	}
+	if got := seqOf(t, newSQN); got != 1051 {
+		t.Errorf("Expected SEQ 1051, got %d", got)
+	}
+
+	// USIM behind the clock: the clock SEQ is used.
+	_, newSQN, err = gen.Resync(sub, hex.EncodeToString(randBytes), auts(500), "")
+	if err != nil {
+		t.Fatalf("Resync failed: %v", err)
+	}
+	if got := seqOf(t, newSQN); got != 1000 {
+		t.Errorf("Expected SEQ 1000, got %d", got)
+	}
+
+	// The far-ahead limit is measured from the clock, not the stored SQN.
+	if _, _, err := gen.Resync(sub, hex.EncodeToString(randBytes), auts(1101), ""); err == nil {
+		t.Error("Expected resync to be rejected for SQN_MS far ahead of the clock")
+	}
+}
@@ -0,0 +1,178 @@
+package aka
+
+import "fmt"
+
+// tuakAlgoName is ALGONAME from TS 35.231 6.2.
+const tuakAlgoName = "TUAK1.0"
+
+// Tuak holds the subscriber-specific inputs of the TUAK algorithm set
+// (TS 35.231). Output lengths are given in bytes.
+type Tuak struct {
+	K          []byte // 16 or 32 bytes
+	TOPc       []byte // 32 bytes
+	Iterations int    // number of Keccak permutations, normally 1
+	MACLen     int    // 8, 16 or 32
+	RESLen     int    // 4, 8, 16 or 32
+	CKLen      int    // 16 or 32
+	IKLen      int    // 16 or 32
+}
+
+// NewTuak returns a Tuak with the output lengths used in UMTS/EPS/5G AKA:
+// 64-bit MAC and RES, 128-bit CK and IK, and one Keccak iteration.
+func NewTuak(k, topc []byte) *Tuak {
+	return &Tuak{K: k, TOPc: topc, Iterations: 1, MACLen: 8, RESLen: 8, CKLen: 16, IKLen: 16}
+}
+
+// TuakTOPc derives TOPc from K and TOP (TS 35.231 6.3).
+func TuakTOPc(k, top []byte, iterations int) ([]byte, error) {
+	if len(top) != 32 {
+		return nil, fmt.Errorf("invalid TOP length: %d", len(top))
+	}
+	if err := checkTuakKey(k); err != nil {
+		return nil, err
+	}
+	out := tuakCore(tuakInstance(0x00, k), top, nil, nil, nil, k, iterations)
+	return reversed(out[:32]), nil
+}
+
+// F1 computes MAC-A (TS 35.231 6.4).
+func (t *Tuak) F1(rand, sqn, amf []byte) ([]byte, error) {
+	return t.f1base(0x00, rand, sqn, amf)
+}
+
+// F1Star computes MAC-S (TS 35.231 6.5).
+func (t *Tuak) F1Star(rand, sqn, amf []byte) ([]byte, error) {
+	return t.f1base(0x80, rand, sqn, amf)
+}
+
+func (t *Tuak) f1base(kind byte, rand, sqn, amf []byte) ([]byte, error) {
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+	code, err := tuakLengthCode(t.MACLen, false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAC length: %w", err)
+	}
+	out := tuakCore(tuakInstance(kind|code<<3, t.K), t.TOPc, rand, amf, sqn, t.K, t.Iterations)
+	return reversed(out[:t.MACLen]), nil
+}
+
+// F2345 computes RES, CK, IK and AK (TS 35.231 6.6).
+func (t *Tuak) F2345(rand []byte) (res, ck, ik, ak []byte, err error) {
+	if err := t.validate(); err != nil {
+		return nil, nil, nil, nil, err
+	}
+	code, err := tuakLengthCode(t.RESLen, true)
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("invalid RES length: %w", err)
+	}
+	inst := byte(0x40) | code<<3
+	switch t.CKLen {
+	case 16:
+	case 32:
+		inst |= 0x04
+	default:
+		return nil, nil, nil, nil, fmt.Errorf("invalid CK length: %d", t.CKLen)
+	}
+	switch t.IKLen {
+	case 16:
+	case 32:
+		inst |= 0x02
+	default:
+		return nil, nil, nil, nil, fmt.Errorf("invalid IK length: %d", t.IKLen)
+	}
+
+	out := tuakCore(tuakInstance(inst, t.K), t.TOPc, rand, nil, nil, t.K, t.Iterations)
+	res = reversed(out[:t.RESLen])
+	ck = reversed(out[32 : 32+t.CKLen])
+	ik = reversed(out[64 : 64+t.IKLen])
+	ak = reversed(out[96:102])
+	return res, ck, ik, ak, nil
+}
+
+// F5Star computes AK for resynchronisation (TS 35.231 6.7).
+func (t *Tuak) F5Star(rand []byte) ([]byte, error) {
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+	out := tuakCore(tuakInstance(0xC0, t.K), t.TOPc, rand, nil, nil, t.K, t.Iterations)
+	return reversed(out[96:102]), nil
+}
+
+func (t *Tuak) validate() error {
+	if err := checkTuakKey(t.K); err != nil {
+		return err
+	}
+	if len(t.TOPc) != 32 {
+		return fmt.Errorf("invalid TOPc length: %d", len(t.TOPc))
+	}
+	if t.Iterations < 1 {
+		return fmt.Errorf("invalid Keccak iteration count: %d", t.Iterations)
+	}
+	return nil
+}
+
+func checkTuakKey(k []byte) error {
+	if len(k) != 16 && len(k) != 32 {
+		return fmt.Errorf("invalid K length: %d", len(k))
+	}
+	return nil
+}
+
+// tuakInstance sets the key-length bit (INSTANCE[0]) on a function's
+// INSTANCE value.
+func tuakInstance(inst byte, k []byte) byte {
+	if len(k) == 32 {
+		inst |= 0x01
+	}
+	return inst
+}
+
+// tuakLengthCode returns the 3-bit INSTANCE length code for a MAC or RES of
+// n bytes. Only RES may be 32 bits long.
+func tuakLengthCode(n int, allow32 bool) (byte, error) {
+	switch {
+	case n == 4 && allow32:
+		return 0, nil
+	case n == 8:
+		return 1, nil
+	case n == 16:
+		return 2, nil
+	case n == 32:
+		return 4, nil
+	}
+	return 0, fmt.Errorf("%d bytes", n)
+}
+
+// tuakCore builds the 1600-bit input of TS 35.231 6.1 and runs the Keccak
+// permutation over it. The specification numbers bits from the least
+// significant end of the state, so every multi-byte parameter is written in
+// reverse byte order. Absent parameters (nil) are left as zero.
+func tuakCore(instance byte, top, rand, amf, sqn, k []byte, iterations int) [200]byte {
+	var st [200]byte
+	copyReversed(st[0:32], top)
+	st[32] = instance
+	copyReversed(st[33:40], []byte(tuakAlgoName))
+	copyReversed(st[40:56], rand)
+	copyReversed(st[56:58], amf)
+	copyReversed(st[58:64], sqn)
+	copyReversed(st[64:64+len(k)], k)
+	st[96] = 0x1F
+	st[135] = 0x80
+	for i := 0; i < iterations; i++ {
+		keccakF1600(&st)
+	}
+	return st
+}
+
+func copyReversed(dst, src []byte) {
+	for i := range src {
+		dst[len(src)-1-i] = src[i]
+	}
+}
+
+func reversed(b []byte) []byte {
+	out := make([]byte, len(b))
+	copyReversed(out, b)
+	return out
+}
@@ -0,0 +1,81 @@
+// Package akma keeps the AKMA contexts an AAnF holds between primary
+// authentication and key requests from application functions (TS 33.535).
+package akma
+
+import (
+	"sync"
+	"time"
+)
+
+// Context is the AKMA key material of one subscriber.
+type Context struct {
+	AKID    string
+	SUPI    string
+	KAKMA   string
+	Expires time.Time
+}
+
+// Store is an in-memory set of AKMA contexts that expire after a fixed
+// lifetime. Each SUPI has at most one context.
+type Store struct {
+	mu     sync.Mutex
+	ttl    time.Duration
+	byAKID map[string]*Context
+	bySUPI map[string]string
+	// Now returns the current time. Tests may replace it.
+	Now func() time.Time
+}
+
+// NewStore returns a Store whose contexts live for ttl.
+func NewStore(ttl time.Duration) *Store {
+	return &Store{
+		ttl:    ttl,
+		byAKID: make(map[string]*Context),
+		bySUPI: make(map[string]string),
+		Now:    time.Now,
+	}
+}
+
+// Put sets the expiry of c and stores it. A context from an earlier primary
+// authentication of the same SUPI is replaced, as KAKMA is refreshed on
+// every successful 5G-AKA.
+func (s *Store) Put(c *Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.Now()
+	for akid, old := range s.byAKID {
+		if now.After(old.Expires) {
+			s.remove(akid)
+		}
+	}
+	if old, ok := s.bySUPI[c.SUPI]; ok {
+		s.remove(old)
+	}
+	c.Expires = now.Add(s.ttl)
+	s.byAKID[c.AKID] = c
+	s.bySUPI[c.SUPI] = c.AKID
+}
+
+// Get returns a copy of the context for akid. It returns nil if there is
+// none or it has expired.
+func (s *Store) Get(akid string) *Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.byAKID[akid]
+	if !ok {
+		return nil
+	}
+	if s.Now().After(c.Expires) {
+		s.remove(akid)
+		return nil
+	}
+	cp := *c
+	return &cp
+}
+
+func (s *Store) remove(akid string) {
+	if c, ok := s.byAKID[akid]; ok {
+		delete(s.bySUPI, c.SUPI)
+		delete(s.byAKID, akid)
+	}
+}
@@ -0,0 +1,34 @@
+package akma
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	now := time.Unix(1000, 0)
+	s := NewStore(time.Hour)
+	s.Now = func() time.Time { return now }
+
+	s.Put(&Context{AKID: "0.aa@5gc.mnc001.mcc001.3gppnetwork.org", SUPI: "001010000000001", KAKMA: "01"})
+	if got := s.Get("0.aa@5gc.mnc001.mcc001.3gppnetwork.org"); got == nil || got.KAKMA != "01" {
+		t.Fatalf("Get returned %v", got)
+	}
+
+	// A new primary authentication replaces the old A-KID.
+	s.Put(&Context{AKID: "0.bb@5gc.mnc001.mcc001.3gppnetwork.org", SUPI: "001010000000001", KAKMA: "02"})
+	if got := s.Get("0.aa@5gc.mnc001.mcc001.3gppnetwork.org"); got != nil {
+		t.Error("Expected the replaced A-KID to be gone")
+	}
+	if got := s.Get("0.bb@5gc.mnc001.mcc001.3gppnetwork.org"); got == nil || got.KAKMA != "02" {
+		t.Fatalf("Get returned %v", got)
+	}
+
+	now = now.Add(time.Hour + time.Second)
+	if got := s.Get("0.bb@5gc.mnc001.mcc001.3gppnetwork.org"); got != nil {
+		t.Error("Expected an expired context to be gone")
+	}
+	if len(s.byAKID) != 0 || len(s.bySUPI) != 0 {
+		t.Errorf("Expected empty store, got %d/%d items", len(s.byAKID), len(s.bySUPI))
+	}
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"aka-server/internal/aka"
+	"aka-server/internal/akma"
+	"aka-server/internal/suci"
+
+	"github.com/gin-gonic/gin"
+)
+
+type KAFRequest struct {
+	AKID string `json:"a_kid"`
+	// AFID is the FQDN of the application function.
+	AFID string `json:"af_id"`
+	// UaProtocolID is the optional 5-byte Ua* security protocol identifier
+	// appended to the FQDN in AF_ID, as hex.
+	UaProtocolID string `json:"ua_protocol_id"`
+}
+
+// homePLMN splits the MCC and MNC off imsi using the longest HOME_PLMNS
+// entry that prefixes it. Without a match the MNC is taken to have two
+// digits.
+func (h *Handler) homePLMN(imsi string) (mcc, mnc string) {
+	best := ""
+	for _, plmn := range h.Cfg.HomePLMNs {
+		if strings.HasPrefix(imsi, plmn) && len(plmn) > len(best) {
+			best = plmn
+		}
+	}
+	if best == "" && len(imsi) >= 5 {
+		best = imsi[:5]
+	}
+	if len(best) < 5 {
+		return "", ""
+	}
+	return best[:3], best[3:]
+}
+
+// registerAKMA derives KAKMA and the A-KID from KAUSF after a successful
+// 5G-AKA and stores them. id is the identity from the auth URI; when it is a
+// SUCI its routing indicator and home network identifier are used.
+func (h *Handler) registerAKMA(id, imsi, kausf string) (string, error) {
+	kakma, atid, err := aka.DeriveAKMA(kausf, imsi)
+	if err != nil {
+		return "", err
+	}
+	rid := ""
+	mcc, mnc := h.homePLMN(imsi)
+	if u, err := suci.Parse(id); err == nil {
+		rid, mcc, mnc = u.RoutingIndicator, u.MCC, u.MNC
+	}
+	akid, err := aka.AKMAKeyID(atid, rid, mcc, mnc)
+	if err != nil {
+		return "", err
+	}
+	h.AKMA.Put(&akma.Context{AKID: akid, SUPI: imsi, KAKMA: kakma})
+	return akid, nil
+}
+
+// GetAKMAKey returns KAF for an application function, like
+// Naanf_AKMA_ApplicationKey_Get (TS 33.535 6.2).
+func (h *Handler) GetAKMAKey(c *gin.Context) {
+	var req KAFRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	afID, err := aka.ApplicationID(req.AFID, req.UaProtocolID)
+	if err != nil || req.AKID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a_kid and a valid af_id are required"})
+		return
+	}
+
+	var ctx *akma.Context
+	if h.AKMA != nil {
+		ctx = h.AKMA.Get(req.AKID)
+	}
+	if ctx == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "A-KID not found or expired"})
+		return
+	}
+	kaf, err := aka.DeriveKAF(ctx.KAKMA, afID)
+	if err != nil {
+		slog.Error("KAF derivation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	slog.Info("KAF issued", "imsi", ctx.SUPI, "af_id", req.AFID)
+	c.JSON(http.StatusOK, gin.H{
+		"kaf":        kaf,
+		"supi":       "imsi-" + ctx.SUPI,
+		"expires_at": ctx.Expires,
+	})
+}
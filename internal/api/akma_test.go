@@ -0,0 +1,56 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"aka-server/internal/aka"
+	"aka-server/internal/akma"
+	"aka-server/internal/config"
+)
+
+func TestAKMAAfterConfirm(t *testing.T) {
+	h, r := newConfirmHandler()
+	h.Cfg = &config.Config{HomePLMNs: []string{"001001"}}
+	h.AKMA = akma.NewStore(time.Hour)
+	r.POST("/api/v1/akma/kaf", h.GetAKMAKey)
+
+	const imsi = "001001000000001"
+	const kausf = "b33c6dc1b2bca8fd4ddb7baa5e8a3e1de4f8c6e2eeefa3b6e6b1b1c5cfa1b4d2"
+	v := &aka.Vector5G{
+		Rand: "23553cbe9637a89d218ae64dae47bf35", Autn: "55f328b43577b9b94a9ffac354dfafb3",
+		XresStar: "f236a7417272bfb2d66d4d670733b527", Kausf: kausf, Kseaf: "bb",
+	}
+	resp := issuePending(t, h, imsi, Method5GAKA, v)
+	code, out := confirm(t, r, imsi, resp["auth_ctx_id"].(string), v.XresStar)
+	if code != http.StatusOK || out["result"] != ConfirmSuccess {
+		t.Fatalf("Expected success, got %d %v", code, out)
+	}
+
+	kakma, atid, _ := aka.DeriveAKMA(kausf, imsi)
+	wantAKID := "0." + atid + "@5gc.mnc001.mcc001.3gppnetwork.org"
+	if out["a_kid"] != wantAKID {
+		t.Fatalf("Unexpected A-KID %s", out["a_kid"])
+	}
+
+	getKAF := func(akid string) (int, map[string]string) {
+		body, _ := json.Marshal(KAFRequest{AKID: akid, AFID: "app.example.com"})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/akma/kaf", bytes.NewReader(body)))
+		var out map[string]string
+		_ = json.Unmarshal(w.Body.Bytes(), &out)
+		return w.Code, out
+	}
+	afID, _ := aka.ApplicationID("app.example.com", "")
+	wantKAF, _ := aka.DeriveKAF(kakma, afID)
+	if code, out := getKAF(wantAKID); code != http.StatusOK || out["kaf"] != wantKAF || out["supi"] != "imsi-"+imsi {
+		t.Errorf("Unexpected KAF response %d %v", code, out)
+	}
+	if code, _ := getKAF("0.00@5gc.mnc001.mcc001.3gppnetwork.org"); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown A-KID, got %d", code)
+	}
+}
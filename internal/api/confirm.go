@@ -0,0 +1,148 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"aka-server/internal/aka"
+	"aka-server/internal/authctx"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Confirmation results returned by ConfirmAuth.
+const (
+	ConfirmSuccess = "success"
+	ConfirmFailure = "failure"
+)
+
+type ConfirmRequest struct {
+	AuthCtxID string `json:"auth_ctx_id"`
+	// Res is the RES returned by the UE, or RES* for 5g-aka.
+	Res string `json:"res"`
+}
+
+// pendingResult is a pending context together with the response it belongs
+// to. The context is stored, and its ID added to the response, only after
+// the issuing transaction has committed.
+type pendingResult struct {
+	ctx  *authctx.Context
+	resp gin.H
+}
+
+// publishPending stores the pending contexts of a committed request and
+// adds their IDs to the responses.
+func (h *Handler) publishPending(pending []pendingResult) error {
+	for _, p := range pending {
+		if err := h.Pending.Add(p.ctx); err != nil {
+			return err
+		}
+		p.resp["auth_ctx_id"] = p.ctx.ID
+	}
+	return nil
+}
+
+// pendingVector builds a pending context holding the expected response of
+// out and the vector the front-end gets in its place: XRES is replaced by
+// HXRES and the context ID, and for 5g-aka KAUSF and KSEAF, and with an EAP
+// identity the MSK and EMSK, are held back until confirmation.
+func (h *Handler) pendingVector(imsi, method string, out any) (pendingResult, error) {
+	ctx := &authctx.Context{IMSI: imsi, Method: method}
+	var resp gin.H
+	switch v := out.(type) {
+	case *aka.AuthVector:
+		ctx.Rand, ctx.XRES = v.Rand, v.Xres
+		resp = gin.H{"rand": v.Rand, "autn": v.Autn, "ck": v.Ck, "ik": v.Ik}
+	case *aka.EAPVector:
+		// K_encr and K_aut protect the challenge itself; the session keys
+		// wait for the RES.
+		ctx.Rand, ctx.XRES = v.Rand, v.Xres
+		ctx.Withheld = map[string]string{"msk": v.MSK, "emsk": v.EMSK}
+		if v.MK != "" {
+			ctx.Withheld["mk"] = v.MK
+		}
+		if v.KRe != "" {
+			ctx.Withheld["k_re"] = v.KRe
+		}
+		resp = gin.H{"rand": v.Rand, "autn": v.Autn, "k_encr": v.KEncr, "k_aut": v.KAut}
+	case *aka.EPSVector:
+		ctx.Rand, ctx.XRES = v.Rand, v.Xres
+		resp = gin.H{"rand": v.Rand, "autn": v.Autn, "kasme": v.Kasme}
+	case *aka.Vector5G:
+		ctx.Rand, ctx.XRES, ctx.Kausf, ctx.Kseaf = v.Rand, v.XresStar, v.Kausf, v.Kseaf
+		resp = gin.H{"rand": v.Rand, "autn": v.Autn, "hxres_star": v.HxresStar}
+	default:
+		return pendingResult{}, fmt.Errorf("confirmation is not supported for %T", out)
+	}
+
+	if _, ok := out.(*aka.Vector5G); !ok {
+		hxres, err := aka.HashRES(ctx.Rand, ctx.XRES)
+		if err != nil {
+			return pendingResult{}, err
+		}
+		resp["hxres"] = hxres
+	}
+	return pendingResult{ctx: ctx, resp: resp}, nil
+}
+
+// ConfirmAuth checks the RES returned by the UE against a pending context
+// created by GenerateAuthVector with confirm set. Each context can be
+// confirmed once. The SUPI is only disclosed on success.
+func (h *Handler) ConfirmAuth(c *gin.Context) {
+	var req ConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	res, err := hex.DecodeString(req.Res)
+	if err != nil || req.AuthCtxID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "auth_ctx_id and hex res are required"})
+		return
+	}
+
+	imsi, _, err := h.resolveIdentity(c.Request.Context(), c.Param("imsi"))
+	if err != nil {
+		c.JSON(identityErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := h.Pending.Take(req.AuthCtxID, imsi)
+	if ctx == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Authentication context not found or expired"})
+		return
+	}
+
+	xres, err := hex.DecodeString(ctx.XRES)
+	if err != nil || subtle.ConstantTimeCompare(res, xres) != 1 {
+		slog.Warn("RES verification failed", "imsi", imsi, "method", ctx.Method)
+		c.JSON(http.StatusOK, gin.H{"result": ConfirmFailure})
+		return
+	}
+
+	slog.Info("RES verified", "imsi", imsi, "method", ctx.Method)
+	resp := gin.H{"result": ConfirmSuccess, "supi": "imsi-" + imsi}
+	if ctx.Kseaf != "" {
+		resp["kseaf"] = ctx.Kseaf
+	}
+	for k, v := range ctx.Withheld {
+		resp[k] = v
+	}
+	if b := ctx.Bootstrap; b != nil {
+		h.GBA.Put(b)
+		resp["b_tid"] = b.BTID
+		resp["impi"] = b.IMPI
+		resp["expires_at"] = b.Expires
+	}
+	if ctx.Kausf != "" && h.AKMA != nil {
+		akid, err := h.registerAKMA(c.Param("imsi"), imsi, ctx.Kausf)
+		if err != nil {
+			slog.Error("Failed to derive AKMA keys", "imsi", imsi, "error", err)
+		} else {
+			resp["a_kid"] = akid
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"aka-server/internal/aka"
+	"aka-server/internal/authctx"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newConfirmHandler() (*Handler, *gin.Engine) {
+	gin.SetMode(gin.TestMode)
+	h := &Handler{Pending: authctx.NewStore(time.Minute)}
+	r := gin.New()
+	r.POST("/api/v1/auth/:imsi/confirm", h.ConfirmAuth)
+	return h, r
+}
+
+func confirm(t *testing.T, r *gin.Engine, imsi, ctxID, res string) (int, map[string]string) {
+	t.Helper()
+	body, _ := json.Marshal(ConfirmRequest{AuthCtxID: ctxID, Res: res})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/auth/"+imsi+"/confirm", bytes.NewReader(body)))
+	var out map[string]string
+	_ = json.Unmarshal(w.Body.Bytes(), &out)
+	return w.Code, out
+}
+
+// issuePending builds a pending context for out and publishes it, as
+// issueVectors does after the commit.
+func issuePending(t *testing.T, h *Handler, imsi, method string, out any) gin.H {
+	t.Helper()
+	p, err := h.pendingVector(imsi, method, out)
+	if err != nil {
+		t.Fatalf("pendingVector failed: %v", err)
+	}
+	if err := h.publishPending([]pendingResult{p}); err != nil {
+		t.Fatalf("publishPending failed: %v", err)
+	}
+	return p.resp
+}
+
+func TestConfirmAuth(t *testing.T) {
+	h, r := newConfirmHandler()
+	const imsi = "001010000000001"
+	vec := &aka.AuthVector{
+		Rand: "23553cbe9637a89d218ae64dae47bf35",
+		Autn: "55f328b43577b9b94a9ffac354dfafb3",
+		Xres: "a54211d5e3ba50bf",
+		Ck:   "b40ba9a3c58b2a05bbf0d987b21bf8cb",
+		Ik:   "f769bcd751044604127672711c6d3441",
+	}
+
+	resp := issuePending(t, h, imsi, MethodEAPAKA, vec)
+	if _, ok := resp["xres"]; ok {
+		t.Fatal("XRES must not be returned in confirm mode")
+	}
+	want, _ := aka.HashRES(vec.Rand, vec.Xres)
+	if resp["hxres"] != want {
+		t.Errorf("Unexpected HXRES %v", resp["hxres"])
+	}
+	ctxID := resp["auth_ctx_id"].(string)
+
+	// Wrong IMSI: not found, and the context stays usable.
+	if code, _ := confirm(t, r, "001010000000002", ctxID, vec.Xres); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for another IMSI, got %d", code)
+	}
+	if code, out := confirm(t, r, imsi, ctxID, vec.Xres); code != http.StatusOK || out["result"] != ConfirmSuccess {
+		t.Errorf("Expected success, got %d %v", code, out)
+	}
+	if code, _ := confirm(t, r, imsi, ctxID, vec.Xres); code != http.StatusNotFound {
+		t.Errorf("Expected 404 on second confirmation, got %d", code)
+	}
+
+	resp = issuePending(t, h, imsi, MethodEAPAKA, vec)
+	if code, out := confirm(t, r, imsi, resp["auth_ctx_id"].(string), "0000000000000000"); code != http.StatusOK || out["result"] != ConfirmFailure {
+		t.Errorf("Expected failure, got %d %v", code, out)
+	}
+}
+
+func TestConfirmAuth5G(t *testing.T) {
+	h, r := newConfirmHandler()
+	const imsi = "001010000000001"
+	v := &aka.Vector5G{
+		Rand: "23553cbe9637a89d218ae64dae47bf35", Autn: "55f328b43577b9b94a9ffac354dfafb3",
+		XresStar: "f236a7417272bfb2d66d4d670733b527", HxresStar: "hx",
+		Kausf: "aa", Kseaf: "bb",
+	}
+	resp := issuePending(t, h, imsi, Method5GAKA, v)
+	for _, k := range []string{"xres_star", "kausf", "kseaf"} {
+		if _, ok := resp[k]; ok {
+			t.Errorf("%s must be withheld until confirmation", k)
+		}
+	}
+	// A null-scheme SUCI for the same IMSI resolves to the same context.
+	code, out := confirm(t, r, "suci-0-001-01-0-0-0-0000000001", resp["auth_ctx_id"].(string), v.XresStar)
+	if code != http.StatusOK || out["result"] != ConfirmSuccess || out["kseaf"] != "bb" || out["supi"] != "imsi-"+imsi {
+		t.Errorf("Expected success with KSEAF and SUPI, got %d %v", code, out)
+	}
+}
+
+func TestConfirmAuthEAPKeys(t *testing.T) {
+	h, r := newConfirmHandler()
+	const imsi = "001010000000001"
+	vec := &aka.AuthVector{
+		Rand: "23553cbe9637a89d218ae64dae47bf35",
+		Autn: "55f328b43577b9b94a9ffac354dfafb3",
+		Xres: "a54211d5e3ba50bf",
+		Ck:   "b40ba9a3c58b2a05bbf0d987b21bf8cb",
+		Ik:   "f769bcd751044604127672711c6d3441",
+	}
+	ev, err := aka.NewEAPVector(vec, "0"+imsi+"@wlan.mnc001.mcc001.3gppnetwork.org", false)
+	if err != nil {
+		t.Fatalf("NewEAPVector failed: %v", err)
+	}
+	resp := issuePending(t, h, imsi, MethodEAPAKA, ev)
+	if resp["k_aut"] != ev.KAut {
+		t.Error("K_aut is needed for the challenge")
+	}
+	for _, k := range []string{"xres", "msk", "emsk", "mk"} {
+		if _, ok := resp[k]; ok {
+			t.Errorf("%s must be withheld until confirmation", k)
+		}
+	}
+	code, out := confirm(t, r, imsi, resp["auth_ctx_id"].(string), vec.Xres)
+	if code != http.StatusOK || out["msk"] != ev.MSK || out["emsk"] != ev.EMSK || out["mk"] != ev.MK {
+		t.Errorf("Expected success with MSK, EMSK and MK, got %d %v", code, out)
+	}
+}
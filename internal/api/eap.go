@@ -0,0 +1,199 @@
+package api
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"aka-server/internal/eapid"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PseudonymRequest struct {
+	// Identity is the IMSI or SUCI of the subscriber.
+	Identity string `json:"identity"`
+	// Method is eap-aka (default) or eap-aka-prime.
+	Method string `json:"method"`
+}
+
+type ReauthIDRequest struct {
+	Identity string `json:"identity"`
+	Method   string `json:"method"`
+	// MK is the EAP-AKA master key, or K_re for EAP-AKA'.
+	MK   string `json:"mk"`
+	KAut string `json:"k_aut"`
+	// Counter is the AT_COUNTER value last used with these keys, 0 after a
+	// full authentication.
+	Counter int `json:"counter"`
+}
+
+type ReauthRequest struct {
+	ReauthID string `json:"reauth_id"`
+}
+
+// eapPrime reports whether method selects EAP-AKA' identities.
+func eapPrime(method string) (bool, error) {
+	switch method {
+	case "", MethodEAPAKA:
+		return false, nil
+	case MethodEAPAKAPrime:
+		return true, nil
+	}
+	return false, fmt.Errorf("unsupported method: %q", method)
+}
+
+// validateReauthKeys checks the key lengths of RFC 4187 7 (MK 160 bits,
+// K_aut 128 bits) or RFC 9048 3.3 (K_re and K_aut 256 bits).
+func validateReauthKeys(prime bool, mkHex, kAutHex string) error {
+	mkLen, kAutLen := 20, 16
+	if prime {
+		mkLen, kAutLen = 32, 32
+	}
+	if mk, err := hex.DecodeString(mkHex); err != nil || len(mk) != mkLen {
+		return fmt.Errorf("mk must be %d hex bytes", mkLen)
+	}
+	if kAut, err := hex.DecodeString(kAutHex); err != nil || len(kAut) != kAutLen {
+		return fmt.Errorf("k_aut must be %d hex bytes", kAutLen)
+	}
+	return nil
+}
+
+// subscriberIMSI resolves id and checks that the subscriber exists. It
+// writes the error response and returns false on failure.
+func (h *Handler) subscriberIMSI(c *gin.Context, id string) (string, bool) {
+	imsi, _, err := h.resolveIdentity(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(identityErrorStatus(err), gin.H{"error": err.Error()})
+		return "", false
+	}
+	sub, err := h.Repo.GetSubscriber(c.Request.Context(), imsi)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return "", false
+	}
+	if sub == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscriber not found"})
+		return "", false
+	}
+	return imsi, true
+}
+
+// CreatePseudonym issues a new encrypted pseudonym for a subscriber.
+func (h *Handler) CreatePseudonym(c *gin.Context) {
+	var req PseudonymRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	prime, err := eapPrime(req.Method)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	imsi, ok := h.subscriberIMSI(c, req.Identity)
+	if !ok {
+		return
+	}
+
+	pseudonym, err := h.Identities.Encode(imsi, eapid.PseudonymPrefix(prime))
+	if err != nil {
+		slog.Error("Failed to create pseudonym", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create pseudonym"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"pseudonym": pseudonym})
+}
+
+// CreateReauthID issues a fast re-authentication identity bound to the keys
+// of a completed full authentication.
+func (h *Handler) CreateReauthID(c *gin.Context) {
+	var req ReauthIDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	prime, err := eapPrime(req.Method)
+	if err == nil {
+		err = validateReauthKeys(prime, req.MK, req.KAut)
+	}
+	if err == nil && req.Counter < 0 {
+		err = fmt.Errorf("counter must not be negative")
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	imsi, ok := h.subscriberIMSI(c, req.Identity)
+	if !ok {
+		return
+	}
+
+	id, err := h.newReauthID(imsi, prime, req.Counter, req.MK, req.KAut)
+	if err != nil {
+		slog.Error("Failed to create re-authentication identity", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create re-authentication identity"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reauth_id": id})
+}
+
+// newReauthID stores a re-authentication context under a new identity.
+func (h *Handler) newReauthID(imsi string, prime bool, counter int, mk, kAut string) (string, error) {
+	id, err := h.Identities.Encode(imsi, eapid.ReauthPrefix(prime))
+	if err != nil {
+		return "", err
+	}
+	h.Reauth.Put(&eapid.ReauthContext{ID: id, IMSI: imsi, Prime: prime, Counter: counter, MK: mk, KAut: kAut})
+	return id, nil
+}
+
+// Reauthenticate consumes a re-authentication identity and returns the
+// state for the next fast re-authentication (RFC 4187 5.4): the stored keys,
+// the incremented counter and a fresh next_reauth_id. A 404 tells the
+// EAP server to fall back to full authentication.
+func (h *Handler) Reauthenticate(c *gin.Context) {
+	var req ReauthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	nai, err := parseNAI(req.ReauthID)
+	if err == nil && nai.Type != eapid.Reauth {
+		err = fmt.Errorf("%w: %s identity is not a re-authentication identity", errInvalidIdentity, nai.Type)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// An identity that no longer decrypts is unknown rather than invalid,
+	// so the EAP server falls back to full authentication.
+	if imsi, _, err := h.Identities.Decode(nai.Username); err == nil {
+		if err := h.checkRealm(nai, imsi); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	ctx := h.Reauth.Take(nai.Username)
+	if ctx == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Re-authentication identity not found or expired"})
+		return
+	}
+
+	counter := ctx.Counter + 1
+	next, err := h.newReauthID(ctx.IMSI, ctx.Prime, counter, ctx.MK, ctx.KAut)
+	if err != nil {
+		slog.Error("Failed to create re-authentication identity", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create re-authentication identity"})
+		return
+	}
+	slog.Info("Fast re-authentication", "imsi", ctx.IMSI, "counter", counter)
+	c.JSON(http.StatusOK, gin.H{
+		"supi":           "imsi-" + ctx.IMSI,
+		"counter":        counter,
+		"mk":             ctx.MK,
+		"k_aut":          ctx.KAut,
+		"next_reauth_id": next,
+	})
+}
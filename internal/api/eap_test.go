@@ -0,0 +1,132 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"aka-server/internal/config"
+	"aka-server/internal/eapid"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestResolveTemporaryIdentity(t *testing.T) {
+	h := &Handler{Cfg: &config.Config{HomePLMNs: []string{"00101"}}, Identities: eapid.NewRandomCodec()}
+	const imsi = "001010123456789"
+	pseudonym, _ := h.Identities.Encode(imsi, eapid.PrefixAKAPrimePseudonym)
+
+	for _, id := range []string{
+		imsi,
+		"0" + imsi + "@wlan.mnc001.mcc001.3gppnetwork.org",
+		"6" + imsi + "@nai.epc.mnc001.mcc001.3gppnetwork.org",
+		pseudonym,
+		pseudonym + "@wlan.mnc001.mcc001.3gppnetwork.org",
+	} {
+		got, _, err := h.resolveIdentity(context.Background(), id)
+		if err != nil || got != imsi {
+			t.Errorf("resolveIdentity(%q) = %s, %v", id, got, err)
+		}
+	}
+
+	stale, _ := eapid.NewRandomCodec().Encode(imsi, eapid.PrefixAKAPseudonym)
+	if _, _, err := h.resolveIdentity(context.Background(), stale); identityErrorStatus(err) != http.StatusBadRequest {
+		t.Errorf("Expected a bad request for a pseudonym under another key, got %v", err)
+	}
+
+	for id, want := range map[string]error{
+		"0" + imsi + "@wlan.mnc410.mcc310.3gppnetwork.org": eapid.ErrForeignRealm,
+		"1" + imsi + "@wlan.mnc001.mcc001.3gppnetwork.org": eapid.ErrUnknownPrefix,
+		"subscriber": eapid.ErrUnknownPrefix,
+		"0abc":       eapid.ErrInvalidIMSI,
+		"6" + imsi:   eapid.ErrMissingRealm,
+	} {
+		_, _, err := h.resolveIdentity(context.Background(), id)
+		if identityErrorStatus(err) != http.StatusBadRequest || !errors.Is(err, want) {
+			t.Errorf("resolveIdentity(%q) = %v, want %v", id, err, want)
+		}
+	}
+}
+
+func TestReauthenticate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &Handler{Identities: eapid.NewRandomCodec(), Reauth: eapid.NewReauthStore(time.Hour)}
+	r := gin.New()
+	r.POST("/api/v1/eap/reauth", h.Reauthenticate)
+
+	reauth := func(id string) (int, map[string]any) {
+		body, _ := json.Marshal(ReauthRequest{ReauthID: id})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/eap/reauth", bytes.NewReader(body)))
+		var out map[string]any
+		_ = json.Unmarshal(w.Body.Bytes(), &out)
+		return w.Code, out
+	}
+
+	const mk = "0123456789abcdef0123456789abcdef01234567"
+	id, err := h.newReauthID("001010123456789", false, 0, mk, "00112233445566778899aabbccddeeff")
+	if err != nil {
+		t.Fatalf("newReauthID failed: %v", err)
+	}
+	if id[0] != eapid.PrefixAKAReauth {
+		t.Errorf("Unexpected re-authentication identity %s", id)
+	}
+
+	code, out := reauth(id)
+	if code != http.StatusOK || out["counter"] != 1.0 || out["mk"] != mk || out["supi"] != "imsi-001010123456789" {
+		t.Fatalf("Unexpected response %d %v", code, out)
+	}
+	if code, _ := reauth(id); code != http.StatusNotFound {
+		t.Errorf("Expected 404 when reusing a re-authentication identity, got %d", code)
+	}
+	code, out = reauth(out["next_reauth_id"].(string) + "@wlan.mnc001.mcc001.3gppnetwork.org")
+	if code != http.StatusOK || out["counter"] != 2.0 {
+		t.Errorf("Expected counter 2 with next_reauth_id, got %d %v", code, out)
+	}
+	if code, _ := reauth("0001010123456789"); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a permanent identity, got %d", code)
+	}
+}
+
+func TestAuthVectorEAPIdentityChecks(t *testing.T) {
+	const imsi = "001010123456789"
+	for _, home := range [][]string{nil, {"00101"}} {
+		r := newTestRouter(t, func(cfg *config.Config) { cfg.HomePLMNs = home })
+		doJSON(r, http.MethodPost, "/api/v1/subscribers", map[string]string{
+			"imsi": imsi,
+			"ki":   "465b5ce8b199b49faa5f0a2ee238a6bc",
+			"opc":  "cd63cb71954a9f4e48a5994e37a02baf",
+			"sqn":  "000000000020",
+			"amf":  "8000",
+		})
+		_, out := doJSON(r, http.MethodPost, "/api/v1/eap/pseudonyms", map[string]string{"identity": imsi, "method": MethodEAPAKAPrime})
+		pseudonym, _ := out["pseudonym"].(string)
+
+		eapAKA := map[string]string{"method": MethodEAPAKA}
+		eapAKAPrime := map[string]string{"method": MethodEAPAKAPrime, "access_network_name": "WLAN"}
+		for _, tt := range []struct {
+			id   string
+			body map[string]string
+			want int
+		}{
+			{"0" + imsi + "@wlan.mnc001.mcc001.3gppnetwork.org", eapAKA, http.StatusOK},
+			{"6" + imsi + "@wlan.mnc001.mcc001.3gppnetwork.org", eapAKAPrime, http.StatusOK},
+			{pseudonym + "@wlan.mnc001.mcc001.3gppnetwork.org", eapAKAPrime, http.StatusOK},
+			{"0" + imsi + "@wlan.mnc410.mcc310.3gppnetwork.org", eapAKA, http.StatusBadRequest},
+			{"0" + imsi + "@example.com", eapAKA, http.StatusBadRequest},
+			{pseudonym + "@wlan.mnc410.mcc310.3gppnetwork.org", eapAKAPrime, http.StatusBadRequest},
+			{"0" + imsi + "@wlan.mnc001.mcc001.3gppnetwork.org", eapAKAPrime, http.StatusBadRequest},
+			{"6" + imsi + "@wlan.mnc001.mcc001.3gppnetwork.org", eapAKA, http.StatusBadRequest},
+			{pseudonym, eapAKA, http.StatusBadRequest},
+		} {
+			if code, out := doJSON(r, http.MethodPost, "/api/v1/auth/"+tt.id, tt.body); code != tt.want {
+				t.Errorf("HOME_PLMNS=%v %s %s: got %d %v, want %d", home, tt.body["method"], tt.id, code, out, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+
+	"aka-server/internal/aka"
+	"aka-server/internal/authctx"
+	"aka-server/internal/gba"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BootstrapRequest struct {
+	// Rand and Auts carry a resynchronization as for the auth endpoint.
+	Rand string `json:"rand"`
+	Auts string `json:"auts"`
+	// IMPI defaults to the IMPI derived from the IMSI.
+	IMPI   string `json:"impi"`
+	NodeID string `json:"node_id"`
+}
+
+type KsNAFRequest struct {
+	BTID string `json:"b_tid"`
+	// NAFID is the FQDN of the NAF.
+	NAFID string `json:"naf_id"`
+	// UaProtocolID is the optional 5-byte Ua security protocol identifier
+	// appended to the FQDN in NAF_Id, as hex.
+	UaProtocolID string `json:"ua_protocol_id"`
+}
+
+// bootstrap builds the pending context of a bootstrapping run and the
+// challenge for the UE. The bootstrapping context rides on the pending
+// context and is only published under its B-TID once ConfirmAuth has
+// checked the RES. Ks stays on the server.
+func (h *Handler) bootstrap(imsi, impi string, vec *aka.AuthVector) (pendingResult, error) {
+	mcc, mnc := h.homePLMN(imsi)
+	bsfName := h.Cfg.GBABSFName
+	if bsfName == "" {
+		bsfName = aka.BSFName(mcc, mnc)
+	}
+	if impi == "" {
+		impi = aka.IMPI(imsi, mcc, mnc)
+	}
+	g, err := aka.DeriveGBA(vec, bsfName)
+	if err != nil {
+		return pendingResult{}, err
+	}
+	hxres, err := aka.HashRES(g.Rand, g.Xres)
+	if err != nil {
+		return pendingResult{}, err
+	}
+	ctx := &authctx.Context{
+		IMSI:      imsi,
+		Method:    MethodGBA,
+		Rand:      g.Rand,
+		XRES:      g.Xres,
+		Bootstrap: &gba.Context{BTID: g.BTID, IMSI: imsi, IMPI: impi, Rand: g.Rand, Ks: g.Ks},
+	}
+	resp := gin.H{
+		"rand":  g.Rand,
+		"autn":  g.Autn,
+		"hxres": hxres,
+		"impi":  impi,
+	}
+	return pendingResult{ctx: ctx, resp: resp}, nil
+}
+
+// Bootstrap runs GBA bootstrapping (TS 33.220 4.5.2) for the subscriber in
+// the URI. It always works in confirm mode: the UE's RES goes to
+// POST /auth/:imsi/confirm, which returns the B-TID on success.
+func (h *Handler) Bootstrap(c *gin.Context) {
+	var body BootstrapRequest
+	if err := c.ShouldBindJSON(&body); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.issueVectors(c, &AuthRequest{
+		Method:  MethodGBA,
+		Confirm: true,
+		Rand:    body.Rand,
+		Auts:    body.Auts,
+		NodeID:  body.NodeID,
+		impi:    body.IMPI,
+	})
+}
+
+// GetKsNAF derives Ks_(ext)_NAF for a NAF from a bootstrapping context
+// (TS 33.220 4.5.3).
+func (h *Handler) GetKsNAF(c *gin.Context) {
+	var req KsNAFRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	nafID, err := aka.ApplicationID(req.NAFID, req.UaProtocolID)
+	if err != nil || req.BTID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "b_tid and a valid naf_id are required"})
+		return
+	}
+
+	ctx := h.GBA.Get(req.BTID)
+	if ctx == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "B-TID not found or expired"})
+		return
+	}
+	ksNAF, err := aka.DeriveKsNAF(ctx.Ks, ctx.Rand, ctx.IMPI, nafID)
+	if err != nil {
+		slog.Error("Ks_NAF derivation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	slog.Info("Ks_NAF issued", "imsi", ctx.IMSI, "naf_id", req.NAFID)
+	c.JSON(http.StatusOK, gin.H{
+		"ks_naf":     ksNAF,
+		"impi":       ctx.IMPI,
+		"expires_at": ctx.Expires,
+	})
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"aka-server/internal/aka"
+	"aka-server/internal/authctx"
+	"aka-server/internal/config"
+	"aka-server/internal/gba"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGBABootstrapAndKsNAF(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &Handler{
+		Cfg:     &config.Config{HomePLMNs: []string{"00101"}},
+		Pending: authctx.NewStore(time.Minute),
+		GBA:     gba.NewStore(time.Hour),
+	}
+	r := gin.New()
+	r.POST("/api/v1/auth/:imsi/confirm", h.ConfirmAuth)
+	r.POST("/api/v1/gba/ks-naf", h.GetKsNAF)
+
+	const imsi = "001010123456789"
+	const btid = "I1U8vpY3qJ0hiuZNrke/NQ==@bsf.mnc001.mcc001.pub.3gppnetwork.org"
+	vec := &aka.AuthVector{
+		Rand: "23553cbe9637a89d218ae64dae47bf35",
+		Autn: "55f328b43577b9b94a9ffac354dfafb3",
+		Xres: "a54211d5e3ba50bf",
+		Ck:   "b40ba9a3c58b2a05bbf0d987b21bf8cb",
+		Ik:   "f769bcd751044604127672711c6d3441",
+	}
+	bootstrap := func() gin.H {
+		p, err := h.bootstrap(imsi, "", vec)
+		if err != nil {
+			t.Fatalf("bootstrap failed: %v", err)
+		}
+		if err := h.publishPending([]pendingResult{p}); err != nil {
+			t.Fatalf("publishPending failed: %v", err)
+		}
+		return p.resp
+	}
+	getKsNAF := func(btid string) (int, map[string]string) {
+		body, _ := json.Marshal(KsNAFRequest{BTID: btid, NAFID: "naf.example.com", UaProtocolID: "0100000002"})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/gba/ks-naf", bytes.NewReader(body)))
+		var out map[string]string
+		_ = json.Unmarshal(w.Body.Bytes(), &out)
+		return w.Code, out
+	}
+
+	resp := bootstrap()
+	for _, k := range []string{"b_tid", "xres", "ks", "ck", "ik"} {
+		if _, ok := resp[k]; ok {
+			t.Errorf("%s must not be part of the challenge", k)
+		}
+	}
+	if code, _ := getKsNAF(btid); code != http.StatusNotFound {
+		t.Errorf("Expected no bootstrapping context before confirmation, got %d", code)
+	}
+
+	// A wrong RES consumes the run without creating a context.
+	if code, out := confirm(t, r, imsi, resp["auth_ctx_id"].(string), "0000000000000000"); code != http.StatusOK || out["result"] != ConfirmFailure {
+		t.Fatalf("Expected failure, got %d %v", code, out)
+	}
+	if code, _ := getKsNAF(btid); code != http.StatusNotFound {
+		t.Errorf("Expected no bootstrapping context after a failed confirmation, got %d", code)
+	}
+
+	resp = bootstrap()
+	code, out := confirm(t, r, imsi, resp["auth_ctx_id"].(string), vec.Xres)
+	if code != http.StatusOK || out["result"] != ConfirmSuccess || out["b_tid"] != btid || out["expires_at"] == "" {
+		t.Fatalf("Expected success with the B-TID, got %d %v", code, out)
+	}
+
+	code, out = getKsNAF(btid)
+	if code != http.StatusOK || out["ks_naf"] != "ae9ecc3c7c17692c1d44d6d68d53a3b1624a706039753ba991e8293f3cfdec08" {
+		t.Errorf("Unexpected Ks_NAF response %d %v", code, out)
+	}
+	if out["impi"] != "001010123456789@ims.mnc001.mcc001.3gppnetwork.org" {
+		t.Errorf("Unexpected IMPI %s", out["impi"])
+	}
+	if code, _ := getKsNAF("AAAA@bsf.mnc001.mcc001.pub.3gppnetwork.org"); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown B-TID, got %d", code)
+	}
+}
+
+func TestGBABootstrapRequireConfirm(t *testing.T) {
+	r := newTestRouter(t, func(cfg *config.Config) { cfg.AuthRequireConfirm = true })
+	const imsi = "001010123456789"
+	doJSON(r, http.MethodPost, "/api/v1/subscribers", map[string]string{
+		"imsi": imsi,
+		"ki":   "465b5ce8b199b49faa5f0a2ee238a6bc",
+		"opc":  "cd63cb71954a9f4e48a5994e37a02baf",
+		"sqn":  "000000000020",
+		"amf":  "8000",
+	})
+	code, out := doJSON(r, http.MethodPost, "/api/v1/gba/bootstrap/"+imsi, nil)
+	if code != http.StatusOK || out["auth_ctx_id"] == nil || out["hxres"] == nil {
+		t.Errorf("Expected a pending challenge with AUTH_REQUIRE_CONFIRM, got %d %v", code, out)
+	}
+}
@@ -1,25 +1,61 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"time"
 
 	"aka-server/internal/aka"
+	"aka-server/internal/akma"
+	"aka-server/internal/authctx"
 	"aka-server/internal/config"
 	"aka-server/internal/db"
+	"aka-server/internal/eapid"
+	"aka-server/internal/gba"
 	"aka-server/internal/model"
 
 	"github.com/gin-gonic/gin"
 )
 
 type Handler struct {
-	Repo *db.Repository
+	Repo db.Store
+	Gen  *aka.Generator
 	Cfg  *config.Config
+	// Pending holds vectors issued in confirm mode until their RES is
+	// confirmed.
+	Pending *authctx.Store
+	// AKMA holds the AKMA contexts derived on 5G-AKA confirmation. It is
+	// nil when AKMA is disabled.
+	AKMA *akma.Store
+	// GBA holds the bootstrapping contexts of the BSF.
+	GBA *gba.Store
+	// Identities encrypts EAP pseudonyms and re-authentication identities.
+	// NewHandler sets a random key; replace it to keep identities valid
+	// across restarts.
+	Identities *eapid.Codec
+	// Reauth holds the fast re-authentication contexts.
+	Reauth *eapid.ReauthStore
 }
 
-func NewHandler(repo *db.Repository, cfg *config.Config) *Handler {
-	return &Handler{Repo: repo, Cfg: cfg}
+func NewHandler(repo db.Store, gen *aka.Generator, cfg *config.Config) *Handler {
+	h := &Handler{
+		Repo:       repo,
+		Gen:        gen,
+		Cfg:        cfg,
+		Pending:    authctx.NewStore(cfg.AuthContextTTL),
+		GBA:        gba.NewStore(cfg.GBAKeyLifetime),
+		Identities: eapid.NewRandomCodec(),
+		Reauth:     eapid.NewReauthStore(cfg.EAPReauthLifetime),
+	}
+	if cfg.AKMAKeyLifetime > 0 {
+		h.AKMA = akma.NewStore(cfg.AKMAKeyLifetime)
+	}
+	return h
 }
 
 func (h *Handler) RegisterRoutes(r *gin.Engine) {
@@ -29,6 +65,7 @@ func (h *Handler) RegisterRoutes(r *gin.Engine) {
 	auth := v1.Group("/auth")
 	auth.Use(IPAllowlist(h.Cfg.AuthAPIAllowedIPs))
 	auth.POST("/:imsi", h.GenerateAuthVector)
+	auth.POST("/:imsi/confirm", h.ConfirmAuth)
 
 	// Subscriber Management Endpoints
 	subs := v1.Group("/subscribers")
@@ -39,6 +76,55 @@ func (h *Handler) RegisterRoutes(r *gin.Engine) {
 	subs.GET("/:imsi", h.GetSubscriber)
 	subs.PUT("/:imsi", h.UpdateSubscriber)
 	subs.DELETE("/:imsi", h.DeleteSubscriber)
+	subs.GET("/:imsi/auth-events", h.ListAuthEvents)
+
+	// Operator Profile Endpoints
+	ops := v1.Group("/operator-profiles")
+	ops.Use(IPAllowlist(h.Cfg.DBAPIAllowedIPs))
+	ops.POST("", h.CreateOperatorProfile)
+	ops.GET("", h.ListOperatorProfiles)
+	ops.GET("/:id", h.GetOperatorProfile)
+	ops.PUT("/:id", h.UpdateOperatorProfile)
+	ops.DELETE("/:id", h.DeleteOperatorProfile)
+
+	// SUCI De-concealment (SIDF)
+	sidf := v1.Group("/suci")
+	sidf.Use(IPAllowlist(h.Cfg.AuthAPIAllowedIPs))
+	sidf.POST("/deconceal", h.DeconcealSUCI)
+
+	// AKMA Application Key Endpoint (AAnF)
+	aanf := v1.Group("/akma")
+	aanf.Use(IPAllowlist(h.Cfg.AuthAPIAllowedIPs))
+	aanf.POST("/kaf", h.GetAKMAKey)
+
+	// GBA Bootstrapping Endpoints (BSF)
+	bsf := v1.Group("/gba")
+	bsf.Use(IPAllowlist(h.Cfg.AuthAPIAllowedIPs))
+	bsf.POST("/bootstrap/:imsi", h.Bootstrap)
+	bsf.POST("/ks-naf", h.GetKsNAF)
+
+	// EAP Temporary Identity Endpoints
+	eap := v1.Group("/eap")
+	eap.Use(IPAllowlist(h.Cfg.AuthAPIAllowedIPs))
+	eap.POST("/pseudonyms", h.CreatePseudonym)
+	eap.POST("/reauth-ids", h.CreateReauthID)
+	eap.POST("/reauth", h.Reauthenticate)
+
+	// Home Network Key Endpoints
+	hnk := v1.Group("/home-network-keys")
+	hnk.Use(IPAllowlist(h.Cfg.DBAPIAllowedIPs))
+	hnk.POST("", h.CreateHomeNetworkKey)
+	hnk.GET("", h.ListHomeNetworkKeys)
+	hnk.GET("/:id", h.GetHomeNetworkKey)
+	hnk.DELETE("/:id", h.DeleteHomeNetworkKey)
+
+	// IMSI Encryption Key Endpoints
+	iek := v1.Group("/imsi-encryption-keys")
+	iek.Use(IPAllowlist(h.Cfg.DBAPIAllowedIPs))
+	iek.POST("", h.CreateIMSIEncryptionKey)
+	iek.GET("", h.ListIMSIEncryptionKeys)
+	iek.GET("/:id", h.GetIMSIEncryptionKey)
+	iek.DELETE("/:id", h.DeleteIMSIEncryptionKey)
 }
 
 // Middleware for IP Allowlist
@@ -67,58 +153,390 @@ func IPAllowlist(allowedIPs []string) gin.HandlerFunc {
 	}
 }
 
+// Authentication methods accepted in AuthRequest.Method. An empty method
+// means MethodEAPAKA.
+const (
+	MethodEAPAKA      = "eap-aka"
+	MethodEAPAKAPrime = "eap-aka-prime"
+	MethodEPSAKA      = "eps-aka"
+	Method5GAKA       = "5g-aka"
+	MethodGSM         = "gsm"
+	MethodIMSAKA      = "ims-aka"
+	// MethodGBA is only reachable through the bootstrap endpoint.
+	MethodGBA = "gba"
+)
+
+// maxTriplets is the largest number of triplets EAP-SIM uses in one
+// challenge (RFC 4186 9.3).
+const maxTriplets = 3
+
 type AuthRequest struct {
-	Rand string `json:"rand"`
-	Auts string `json:"auts"`
+	Rand   string `json:"rand"`
+	Auts   string `json:"auts"`
+	Method string `json:"method"`
+	// ServingNetworkName is required for 5g-aka,
+	// e.g. "5G:mnc001.mcc001.3gppnetwork.org".
+	ServingNetworkName string `json:"serving_network_name"`
+	// AccessNetworkName is required for eap-aka-prime, e.g. "WLAN".
+	AccessNetworkName string `json:"access_network_name"`
+	// MCC and MNC identify the serving PLMN for eps-aka.
+	MCC string `json:"mcc"`
+	MNC string `json:"mnc"`
+	// Count is the number of vectors to return, each with its own SEQ. When
+	// 1 or more, the response is an array; 0 means a single vector.
+	Count int `json:"count"`
+	// NodeID identifies the requesting node for per-node IND allocation.
+	// Defaults to the client IP.
+	NodeID string `json:"node_id"`
+	// Confirm returns HXRES and an auth context ID in place of XRES. The
+	// UE's RES is then checked with POST /auth/:imsi/confirm.
+	Confirm bool `json:"confirm"`
+	// DigestAlgorithm selects AKAv1-MD5 (default) or AKAv2-MD5 for ims-aka.
+	DigestAlgorithm string `json:"digest_algorithm"`
+	// Username and Realm, when set, make ims-aka return HA1.
+	Username string `json:"username"`
+	Realm    string `json:"realm"`
+	// Nonce is the ims-aka challenge a resync answers. With ims-aka, Auts
+	// is the base64 auts digest parameter.
+	Nonce string `json:"nonce"`
+
+	// EAPIdentity is the identity from EAP-Response/Identity or
+	// AT_IDENTITY. When set, eap-aka and eap-aka-prime vectors include the
+	// EAP key hierarchy derived for it.
+	EAPIdentity string `json:"eap_identity"`
+
+	// impi is the IMPI given to the GBA bootstrap endpoint.
+	impi string
+}
+
+// validate checks the request parameters before any SQN is consumed.
+// maxVectors caps Count. For ims-aka it also converts the digest resync
+// parameters into the hex rand and auts used by the other methods.
+func (req *AuthRequest) validate(maxVectors int) error {
+	if req.Method == MethodGSM {
+		maxVectors = min(maxVectors, maxTriplets)
+	}
+	if req.Count < 0 || req.Count > maxVectors {
+		return fmt.Errorf("count must be between 1 and %d (0 or omitted issues a single vector)", maxVectors)
+	}
+	if req.Confirm && (req.Method == MethodGSM || req.Method == MethodIMSAKA) {
+		return fmt.Errorf("confirm is not supported for %s", req.Method)
+	}
+	if req.EAPIdentity != "" && req.Method != "" && req.Method != MethodEAPAKA && req.Method != MethodEAPAKAPrime {
+		return fmt.Errorf("eap_identity is not supported for %s", req.Method)
+	}
+
+	switch req.Method {
+	case "", MethodEAPAKA, MethodGSM, MethodGBA:
+		return nil
+	case MethodEAPAKAPrime:
+		if req.AccessNetworkName == "" {
+			return fmt.Errorf("access_network_name is required for %s", req.Method)
+		}
+		return nil
+	case MethodEPSAKA:
+		_, err := aka.PLMNID(req.MCC, req.MNC)
+		return err
+	case Method5GAKA:
+		return aka.ValidateServingNetworkName(req.ServingNetworkName)
+	case MethodIMSAKA:
+		if err := aka.ValidateDigestAlgorithm(req.DigestAlgorithm); err != nil {
+			return err
+		}
+		return req.decodeDigestResync()
+	default:
+		return fmt.Errorf("unsupported method: %q", req.Method)
+	}
+}
+
+// decodeDigestResync replaces the base64 nonce and auts of an ims-aka
+// resync with the hex RAND and AUTS.
+func (req *AuthRequest) decodeDigestResync() error {
+	if req.Nonce != "" {
+		rand, err := aka.DigestNonceRand(req.Nonce)
+		if err != nil {
+			return err
+		}
+		req.Rand = rand
+	}
+	if req.Auts != "" {
+		auts, err := aka.DecodeDigestAUTS(req.Auts)
+		if err != nil {
+			return err
+		}
+		req.Auts = auts
+	}
+	return nil
+}
+
+// methodName returns the method with the default filled in.
+func (req *AuthRequest) methodName() string {
+	if req.Method == "" {
+		return MethodEAPAKA
+	}
+	return req.Method
+}
+
+// needsSeparationBit reports whether the method requires vectors generated
+// with the AMF separation bit set.
+func (req *AuthRequest) needsSeparationBit() bool {
+	switch req.Method {
+	case MethodEAPAKAPrime, MethodEPSAKA, Method5GAKA:
+		return true
+	}
+	return false
+}
+
+// convertVector turns the generated quintet into the response for the
+// requested method.
+func (req *AuthRequest) convertVector(vec *aka.AuthVector) (any, error) {
+	switch req.Method {
+	case MethodEAPAKAPrime:
+		prime, err := aka.DeriveAKAPrime(vec, req.AccessNetworkName)
+		if err != nil || req.EAPIdentity == "" {
+			return prime, err
+		}
+		return aka.NewEAPVector(prime, req.EAPIdentity, true)
+	case MethodEPSAKA:
+		return aka.DeriveEPS(vec, req.MCC, req.MNC)
+	case Method5GAKA:
+		return aka.Derive5G(vec, req.ServingNetworkName)
+	case MethodGSM:
+		return aka.DeriveTriplet(vec)
+	case MethodIMSAKA:
+		return aka.DeriveDigest(vec, req.DigestAlgorithm, req.Username, req.Realm)
+	default:
+		if req.EAPIdentity != "" {
+			return aka.NewEAPVector(vec, req.EAPIdentity, false)
+		}
+		return vec, nil
+	}
 }
 
 func (h *Handler) GenerateAuthVector(c *gin.Context) {
-	imsi := c.Param("imsi")
 	var req AuthRequest
 	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
 		// It's okay if body is empty, but if it's invalid JSON, error out.
 		// Actually ShouldBindJSON returns error on empty body sometimes depending on content type.
 		// We'll assume empty body is fine.
 	}
-
-	sub, err := h.Repo.GetSubscriber(c.Request.Context(), imsi)
-	if err != nil {
-		slog.Error("Database error", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+	if req.Method == MethodGBA {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "use /gba/bootstrap for gba"})
 		return
 	}
-	if sub == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Subscriber not found"})
+	h.issueVectors(c, &req)
+}
+
+// issueVectors generates the vectors described by req for the identity in
+// the URI and writes the response.
+func (h *Handler) issueVectors(c *gin.Context, req *AuthRequest) {
+	if h.Cfg.AuthRequireConfirm {
+		req.Confirm = true
+	}
+	if err := req.validate(h.Cfg.AuthMaxVectors); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	var vec *aka.AuthVector
-	var newSQN string
-
-	if req.Rand != "" && req.Auts != "" {
-		// Resync
-		slog.Info("Processing Resync", "imsi", imsi)
-		vec, newSQN, err = aka.Resync(sub, req.Rand, req.Auts)
-	} else {
-		// Normal Auth
-		slog.Info("Processing Normal Auth", "imsi", imsi)
-		vec, newSQN, err = aka.GenerateVector(sub)
+	if req.NodeID == "" {
+		req.NodeID = c.ClientIP()
 	}
 
+	ctx := c.Request.Context()
+	imsi, nai, err := h.resolveIdentity(ctx, c.Param("imsi"))
+	if err == nil && nai != nil {
+		err = checkEAPMethod(nai, req.methodName())
+	}
 	if err != nil {
-		slog.Error("AKA generation failed", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		slog.Warn("Failed to resolve identity", "error", err)
+		c.JSON(identityErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	// Update SQN in DB
-	if err := h.Repo.UpdateSQN(c.Request.Context(), imsi, newSQN); err != nil {
+	resync := req.Rand != "" && req.Auts != ""
+	if resync {
+		// Only a RAND we issued recently can carry a genuine AUTS.
+		issued, err := h.Repo.RandIssued(ctx, imsi, req.Rand, time.Now().Add(-h.Cfg.AuthResyncWindow))
+		if err != nil {
+			slog.Error("Failed to look up auth events", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		if !issued {
+			slog.Warn("Resync rejected for unknown RAND", "imsi", imsi)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "rand was not recently issued to this subscriber"})
+			return
+		}
+	}
+
+	var resp any
+	var akaErr error
+	// Pending contexts, and with them GBA bootstrapping contexts, are only
+	// published once the vectors they belong to have been committed.
+	var pending []pendingResult
+
+	// SQN read, vector generation, SQN write and the auth event history
+	// happen under one row lock so that parallel requests for the same IMSI
+	// never share an SQN.
+	err = h.Repo.AdvanceSQN(ctx, imsi, func(sub *model.Subscriber) (string, []*model.AuthEvent, error) {
+		if req.needsSeparationBit() {
+			if sub, akaErr = aka.WithSeparationBit(sub); akaErr != nil {
+				return "", nil, akaErr
+			}
+		}
+
+		n := max(req.Count, 1)
+		cur := *sub
+		var vecs []*aka.AuthVector
+		if resync {
+			// Resync: the first vector comes from the recovered SQN_MS.
+			slog.Info("Processing Resync", "imsi", imsi, "method", req.Method, "count", n)
+			var vec *aka.AuthVector
+			if vec, cur.SQN, akaErr = h.Gen.Resync(&cur, req.Rand, req.Auts, req.NodeID); akaErr != nil {
+				return "", nil, akaErr
+			}
+			vecs = append(vecs, vec)
+		} else {
+			// Normal Auth
+			slog.Info("Processing Normal Auth", "imsi", imsi, "method", req.Method, "count", n)
+		}
+
+		more, lastSQN, err := h.Gen.GenerateVectors(&cur, n-len(vecs), req.NodeID)
+		if err != nil {
+			akaErr = err
+			return "", nil, akaErr
+		}
+		vecs = append(vecs, more...)
+
+		results := make([]any, 0, n)
+		events := make([]*model.AuthEvent, 0, n)
+		for i, vec := range vecs {
+			var out any
+			if out, akaErr = req.convertVector(vec); akaErr != nil {
+				return "", nil, akaErr
+			}
+			if req.Confirm {
+				var p pendingResult
+				if req.Method == MethodGBA {
+					p, akaErr = h.bootstrap(imsi, req.impi, vec)
+				} else {
+					p, akaErr = h.pendingVector(imsi, req.Method, out)
+				}
+				if akaErr != nil {
+					return "", nil, akaErr
+				}
+				pending = append(pending, p)
+				out = p.resp
+			}
+			results = append(results, out)
+
+			ind, err := h.Gen.SQN.IND(vec.SQN)
+			if err != nil {
+				akaErr = err
+				return "", nil, akaErr
+			}
+			events = append(events, &model.AuthEvent{
+				IMSI:   imsi,
+				Rand:   vec.Rand,
+				SQN:    vec.SQN,
+				IND:    int(ind),
+				Method: req.methodName(),
+				Client: c.ClientIP(),
+				NodeID: req.NodeID,
+				Resync: resync && i == 0,
+			})
+		}
+
+		if req.Count == 0 {
+			resp = results[0]
+		} else {
+			resp = results
+		}
+		return lastSQN, events, nil
+	})
+
+	if errors.Is(err, db.ErrSubscriberNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscriber not found"})
+		return
+	}
+	if errors.Is(akaErr, aka.ErrSEQExhausted) {
+		slog.Error("SEQ exhausted", "imsi", imsi)
+		c.JSON(http.StatusConflict, gin.H{"error": akaErr.Error()})
+		return
+	}
+	if errors.Is(akaErr, aka.ErrMACS) {
+		slog.Warn("AUTS rejected", "imsi", imsi, "error", akaErr)
+		c.JSON(http.StatusForbidden, gin.H{"error": akaErr.Error()})
+		return
+	}
+	if errors.Is(akaErr, aka.ErrResyncTooFarAhead) {
+		slog.Warn("AUTS rejected", "imsi", imsi, "error", akaErr)
+		c.JSON(http.StatusBadRequest, gin.H{"error": akaErr.Error()})
+		return
+	}
+	if akaErr != nil {
+		slog.Error("AKA generation failed", "error", akaErr)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": akaErr.Error()})
+		return
+	}
+	if err != nil {
 		slog.Error("Failed to update SQN", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update SQN"})
 		return
 	}
+	if err := h.publishPending(pending); err != nil {
+		slog.Error("Failed to store pending auth contexts", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store pending auth contexts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// normalizeSubscriber fills defaults and rejects unsupported settings before
+// a subscriber is written.
+func normalizeSubscriber(sub *model.Subscriber) error {
+	if sub.Algorithm == "" {
+		sub.Algorithm = aka.AlgorithmMilenage
+	}
+	if sub.SQNProfile == "" {
+		sub.SQNProfile = aka.SQNProfileCounter
+	}
+	if err := aka.ValidateAlgorithm(sub.Algorithm); err != nil {
+		return err
+	}
+	if err := aka.ValidateSQNProfile(sub.SQNProfile); err != nil {
+		return err
+	}
+	if err := aka.ValidateKeyLengths(sub.Algorithm, sub.Ki, sub.Opc); err != nil {
+		return err
+	}
+	if sub.OP != "" {
+		// Only the derived OPc is stored.
+		if sub.Opc != "" {
+			return errors.New("op and opc are mutually exclusive")
+		}
+		opc, err := aka.ComputeOPc(sub.Algorithm, sub.Ki, sub.OP)
+		if err != nil {
+			return err
+		}
+		sub.Opc, sub.OP = opc, ""
+	}
+	return nil
+}
 
-	c.JSON(http.StatusOK, vec)
+// prepareSubscriber normalizes sub and checks that its operator profile
+// exists and that an OPc is either stored or derivable from the profile.
+func (h *Handler) prepareSubscriber(ctx context.Context, sub *model.Subscriber) error {
+	if err := normalizeSubscriber(sub); err != nil {
+		return err
+	}
+	if err := h.resolveOperator(ctx, sub); err != nil {
+		return err
+	}
+	if sub.Opc == "" && (sub.Operator == nil || sub.Operator.OP == "") {
+		return errors.New("opc or op is required unless the operator profile has op")
+	}
+	return checkProfileOP(sub, sub.Operator)
 }
 
 func (h *Handler) CreateSubscriber(c *gin.Context) {
@@ -127,8 +545,17 @@ func (h *Handler) CreateSubscriber(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := h.prepareSubscriber(c.Request.Context(), &sub); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if err := h.Repo.CreateSubscriber(c.Request.Context(), &sub); err != nil {
+	err := h.Repo.CreateSubscriber(c.Request.Context(), &sub)
+	if errors.Is(err, db.ErrOperatorProfileChanged) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
 		slog.Error("Failed to create subscriber", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create subscriber"})
 		return
@@ -157,9 +584,18 @@ func (h *Handler) UpdateSubscriber(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := h.prepareSubscriber(c.Request.Context(), &sub); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	sub.IMSI = imsi // Ensure IMSI matches URL
 
-	if err := h.Repo.UpdateSubscriber(c.Request.Context(), &sub); err != nil {
+	err := h.Repo.UpdateSubscriber(c.Request.Context(), &sub)
+	if errors.Is(err, db.ErrOperatorProfileChanged) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update subscriber"})
 		return
 	}
@@ -198,3 +634,33 @@ func (h *Handler) ListSubscribers(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, subs)
 }
+
+// Page size limits for ListAuthEvents.
+const (
+	defaultAuthEvents = 100
+	maxAuthEvents     = 1000
+)
+
+func (h *Handler) ListAuthEvents(c *gin.Context) {
+	imsi := c.Param("imsi")
+	limit := defaultAuthEvents
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxAuthEvents {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxAuthEvents)})
+			return
+		}
+		limit = n
+	}
+
+	events, err := h.Repo.ListAuthEvents(c.Request.Context(), imsi, limit)
+	if err != nil {
+		slog.Error("Failed to list auth events", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if events == nil {
+		events = []*model.AuthEvent{}
+	}
+	c.JSON(http.StatusOK, events)
+}
@@ -0,0 +1,237 @@
+package api
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"aka-server/internal/aka"
+	"aka-server/internal/config"
+	"aka-server/internal/db"
+	"aka-server/internal/imsicrypt"
+	"aka-server/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestRouter returns a router over a Handler backed by a MemoryStore.
+// Each opt may adjust the configuration first.
+func newTestRouter(t *testing.T, opts ...func(*config.Config)) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{
+		AuthMaxVectors:    5,
+		AuthContextTTL:    30 * time.Second,
+		AuthResyncWindow:  24 * time.Hour,
+		HomePLMNs:         []string{"00101"},
+		GBAKeyLifetime:    time.Hour,
+		EAPReauthLifetime: time.Hour,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	gen, err := aka.NewGenerator(aka.SQNConfig{INDBits: 5, INDAllocation: "fixed", Delta: 1 << 28, ResyncMaxAhead: 1 << 28, TimeGranularity: time.Second})
+	if err != nil {
+		t.Fatalf("NewGenerator failed: %v", err)
+	}
+	r := gin.New()
+	NewHandler(db.NewMemoryStore(), gen, cfg).RegisterRoutes(r)
+	return r
+}
+
+func doJSON(r *gin.Engine, method, path string, body any) (int, map[string]any) {
+	var buf bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&buf).Encode(body)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, path, &buf))
+	var out map[string]any
+	_ = json.Unmarshal(w.Body.Bytes(), &out)
+	return w.Code, out
+}
+
+func TestAuthVectorMemoryStore(t *testing.T) {
+	r := newTestRouter(t)
+	const imsi = "001010123456789"
+	code, _ := doJSON(r, http.MethodPost, "/api/v1/subscribers", map[string]string{
+		"imsi": imsi,
+		"ki":   "465b5ce8b199b49faa5f0a2ee238a6bc",
+		"opc":  "cd63cb71954a9f4e48a5994e37a02baf",
+		"sqn":  "000000000020",
+		"amf":  "8000",
+	})
+	if code != http.StatusCreated {
+		t.Fatalf("CreateSubscriber returned %d", code)
+	}
+
+	code, out := doJSON(r, http.MethodPost, "/api/v1/auth/"+imsi, map[string]string{})
+	if code != http.StatusOK || out["rand"] == nil {
+		t.Fatalf("GenerateAuthVector returned %d %v", code, out)
+	}
+	if code, _ := doJSON(r, http.MethodPost, "/api/v1/auth/001019999999999", map[string]string{}); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown subscriber, got %d", code)
+	}
+	code, sub := doJSON(r, http.MethodGet, "/api/v1/subscribers/"+imsi, nil)
+	if code != http.StatusOK || sub["sqn"] == "000000000020" {
+		t.Errorf("Expected the SQN to advance, got %d %v", code, sub)
+	}
+}
+
+func TestAuthVectorResyncErrors(t *testing.T) {
+	r := newTestRouter(t)
+	sub := &model.Subscriber{
+		IMSI: "001010123456789",
+		Ki:   "465b5ce8b199b49faa5f0a2ee238a6bc",
+		Opc:  "cd63cb71954a9f4e48a5994e37a02baf",
+		SQN:  "000000000020",
+		AMF:  "8000",
+	}
+	doJSON(r, http.MethodPost, "/api/v1/subscribers", sub)
+	_, out := doJSON(r, http.MethodPost, "/api/v1/auth/"+sub.IMSI, map[string]string{})
+	randHex, _ := out["rand"].(string)
+
+	code, _ := doJSON(r, http.MethodPost, "/api/v1/auth/"+sub.IMSI, map[string]string{
+		"rand": randHex,
+		"auts": strings.Repeat("00", 14),
+	})
+	if code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a bad MAC-S, got %d", code)
+	}
+
+	// A genuine AUTS whose SQN_MS is beyond SQN_RESYNC_MAX_AHEAD.
+	alg, err := aka.NewAlgorithmSet(sub)
+	if err != nil {
+		t.Fatalf("NewAlgorithmSet failed: %v", err)
+	}
+	rand, _ := hex.DecodeString(randHex)
+	sqnMS, _ := hex.DecodeString("7fffffffffe0")
+	aks, _ := alg.F5Star(rand)
+	macS, _ := alg.F1Star(rand, sqnMS, []byte{0, 0})
+	auts := make([]byte, 0, 14)
+	for i := range sqnMS {
+		auts = append(auts, sqnMS[i]^aks[i])
+	}
+	auts = append(auts, macS...)
+	code, _ = doJSON(r, http.MethodPost, "/api/v1/auth/"+sub.IMSI, map[string]string{
+		"rand": randHex,
+		"auts": hex.EncodeToString(auts),
+	})
+	if code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for SQN_MS too far ahead, got %d", code)
+	}
+}
+
+func TestSubscriberKeyLengths(t *testing.T) {
+	r := newTestRouter(t)
+	sub := map[string]string{
+		"imsi": "001010123456789",
+		"ki":   strings.Repeat("46", 32),
+		"opc":  "cd63cb71954a9f4e48a5994e37a02baf",
+		"sqn":  "000000000020",
+		"amf":  "8000",
+	}
+	if code, _ := doJSON(r, http.MethodPost, "/api/v1/subscribers", sub); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a 64-hex Ki with milenage, got %d", code)
+	}
+	sub["ki"] = "465b5ce8b199b49faa5f0a2ee238a6bc"
+	sub["opc"] = strings.Repeat("cd", 32)
+	if code, _ := doJSON(r, http.MethodPost, "/api/v1/subscribers", sub); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a 64-hex OPc with milenage, got %d", code)
+	}
+	sub["algorithm"] = "tuak"
+	sub["ki"] = strings.Repeat("46", 32)
+	if code, _ := doJSON(r, http.MethodPost, "/api/v1/subscribers", sub); code != http.StatusCreated {
+		t.Errorf("Expected a 64-hex Ki and TOPc to be accepted for tuak, got %d", code)
+	}
+}
+
+func TestOperatorProfileOP(t *testing.T) {
+	r := newTestRouter(t)
+	const op = "cdc202d5123e20f62b6d676ac72cb318"
+	if code, _ := doJSON(r, http.MethodPost, "/api/v1/operator-profiles", map[string]string{"id": "mvno-a", "op": op}); code != http.StatusCreated {
+		t.Fatalf("CreateOperatorProfile returned %d", code)
+	}
+	sub := map[string]string{
+		"imsi":             "001010123456789",
+		"ki":               "465b5ce8b199b49faa5f0a2ee238a6bc",
+		"sqn":              "000000000020",
+		"amf":              "8000",
+		"algorithm":        "tuak",
+		"operator_profile": "mvno-a",
+	}
+	if code, _ := doJSON(r, http.MethodPost, "/api/v1/subscribers", sub); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a TUAK subscriber with a 16-byte OP, got %d", code)
+	}
+	sub["algorithm"] = "milenage"
+	if code, _ := doJSON(r, http.MethodPost, "/api/v1/subscribers", sub); code != http.StatusCreated {
+		t.Fatalf("CreateSubscriber returned %d", code)
+	}
+
+	code, out := doJSON(r, http.MethodGet, "/api/v1/operator-profiles/mvno-a", nil)
+	if code != http.StatusOK || out["op"] != nil {
+		t.Errorf("GetOperatorProfile returned %d %v; op must be omitted", code, out)
+	}
+	top := strings.Repeat("55", 32)
+	if code, _ := doJSON(r, http.MethodPut, "/api/v1/operator-profiles/mvno-a", map[string]string{"op": top}); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a TOP used by a Milenage subscriber, got %d", code)
+	}
+	if code, _ := doJSON(r, http.MethodPut, "/api/v1/operator-profiles/mvno-a", map[string]string{}); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for removing an OP a subscriber depends on, got %d", code)
+	}
+}
+
+func TestAuthVectorEncryptedIMSI(t *testing.T) {
+	r := newTestRouter(t)
+	const imsi = "001010123456789"
+	doJSON(r, http.MethodPost, "/api/v1/subscribers", map[string]string{
+		"imsi": imsi,
+		"ki":   "465b5ce8b199b49faa5f0a2ee238a6bc",
+		"opc":  "cd63cb71954a9f4e48a5994e37a02baf",
+		"sqn":  "000000000020",
+		"amf":  "8000",
+	})
+	code, key := doJSON(r, http.MethodPost, "/api/v1/imsi-encryption-keys", map[string]string{"id": "CertificateSerialNumber=7"})
+	if code != http.StatusCreated || key["private_key"] != nil {
+		t.Fatalf("CreateIMSIEncryptionKey returned %d %v", code, key)
+	}
+	// A second active key must not get in the way.
+	doJSON(r, http.MethodPost, "/api/v1/imsi-encryption-keys", map[string]string{"id": "CertificateSerialNumber=8"})
+
+	block, _ := pem.Decode([]byte(key["public_key"].(string)))
+	if block == nil {
+		t.Fatalf("public_key is not PEM: %v", key["public_key"])
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("Invalid public key: %v", err)
+	}
+	pub := parsed.(*rsa.PublicKey)
+	id, err := imsicrypt.Encrypt(pub, "0"+imsi+"@wlan.mnc001.mcc001.3gppnetwork.org", "", "")
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	id = strings.NewReplacer("+", "-", "/", "_", "=", "").Replace(id)
+	path := "/api/v1/auth/" + strings.Replace(id, "\x00", "%00", 1)
+
+	for _, suffix := range []string{"", ",CertificateSerialNumber=7"} {
+		code, out := doJSON(r, http.MethodPost, path+suffix, map[string]string{})
+		if code != http.StatusOK || out["rand"] == nil {
+			t.Errorf("GenerateAuthVector(%q) returned %d %v", suffix, code, out)
+		}
+	}
+	if code, _ := doJSON(r, http.MethodPost, path+",CertificateSerialNumber=8", map[string]string{}); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 under the wrong key, got %d", code)
+	}
+	if code, _ := doJSON(r, http.MethodPost, path+",unknown", map[string]string{}); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown key ID, got %d", code)
+	}
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"aka-server/internal/aka"
+	"aka-server/internal/eapid"
+	"aka-server/internal/imsicrypt"
+	"aka-server/internal/suci"
+)
+
+// errInvalidIdentity marks identities that cannot be resolved because of the
+// request itself rather than a server fault.
+var errInvalidIdentity = errors.New("invalid identity")
+
+// resolveIdentity maps the identity in an auth URI to an IMSI. A SUCI is
+// de-concealed with the home network key it names, an encrypted identity is
+// decrypted with an IMSI encryption key, and a string of up to 15 digits is
+// a bare IMSI. Anything else must be an EAP NAI in a home realm: a permanent
+// identity, which needs a realm, carries the IMSI, and a pseudonym or
+// re-authentication identity is decrypted. The parsed NAI is returned for
+// EAP identities and is nil otherwise.
+func (h *Handler) resolveIdentity(ctx context.Context, id string) (string, *eapid.NAI, error) {
+	if suci.IsSUCI(id) {
+		imsi, err := h.deconcealSUCI(ctx, id)
+		return imsi, nil, err
+	}
+	if imsicrypt.IsEncrypted(id) {
+		imsi, err := h.decryptIMSI(ctx, id)
+		return imsi, nil, err
+	}
+	if aka.IsDigits(id, 1, 15) {
+		return id, nil, nil
+	}
+
+	nai, err := parseNAI(id)
+	if err != nil {
+		return "", nil, err
+	}
+	imsi := nai.IMSI
+	if nai.Type != eapid.Permanent {
+		if h.Identities == nil {
+			return "", nil, fmt.Errorf("%w: temporary identities are not enabled", errInvalidIdentity)
+		}
+		if imsi, _, err = h.Identities.Decode(nai.Username); err != nil {
+			return "", nil, fmt.Errorf("%w: %v", errInvalidIdentity, err)
+		}
+	}
+	if err := h.checkRealm(nai, imsi); err != nil {
+		return "", nil, err
+	}
+	return imsi, nai, nil
+}
+
+// parseNAI parses an EAP identity.
+func parseNAI(id string) (*eapid.NAI, error) {
+	nai, err := eapid.ParseNAI(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidIdentity, err)
+	}
+	return nai, nil
+}
+
+// checkRealm checks the realm of nai, which identifies imsi, against
+// HOME_PLMNS. Without HOME_PLMNS the home network is the PLMN of imsi
+// itself, with either MNC length.
+func (h *Handler) checkRealm(nai *eapid.NAI, imsi string) error {
+	var home []string
+	if h.Cfg != nil {
+		home = h.Cfg.HomePLMNs
+	}
+	if len(home) == 0 && len(imsi) >= 6 {
+		home = []string{imsi[:5], imsi[:6]}
+	}
+	if err := nai.CheckHome(home); err != nil {
+		return fmt.Errorf("%w: %w", errInvalidIdentity, err)
+	}
+	return nil
+}
+
+// checkEAPMethod rejects an EAP-AKA identity in an eap-aka-prime request and
+// an EAP-AKA' identity in an eap-aka request. Other methods are not checked.
+func checkEAPMethod(nai *eapid.NAI, method string) error {
+	if method != MethodEAPAKA && method != MethodEAPAKAPrime {
+		return nil
+	}
+	if err := nai.CheckMethod(method == MethodEAPAKAPrime); err != nil {
+		return fmt.Errorf("%w: %w", errInvalidIdentity, err)
+	}
+	return nil
+}
+
+// identityErrorStatus returns the HTTP status for a resolveIdentity error.
+func identityErrorStatus(err error) int {
+	if errors.Is(err, errInvalidIdentity) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
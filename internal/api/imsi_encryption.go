@@ -0,0 +1,159 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"aka-server/internal/aka"
+	"aka-server/internal/eapid"
+	"aka-server/internal/imsicrypt"
+	"aka-server/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// decryptIMSI recovers the IMSI from an encrypted identity with the key it
+// names, or with each stored key if it names none.
+func (h *Handler) decryptIMSI(ctx context.Context, id string) (string, error) {
+	enc, err := imsicrypt.Parse(id)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errInvalidIdentity, err)
+	}
+
+	var keys []*model.IMSIEncryptionKey
+	if enc.KeyID != "" {
+		k, err := h.Repo.GetIMSIEncryptionKey(ctx, enc.KeyID)
+		if err != nil {
+			return "", fmt.Errorf("failed to load IMSI encryption key: %w", err)
+		}
+		if k == nil {
+			return "", fmt.Errorf("%w: no IMSI encryption key with id %q", errInvalidIdentity, enc.KeyID)
+		}
+		keys = append(keys, k)
+	} else if keys, err = h.Repo.ListIMSIEncryptionKeys(ctx); err != nil {
+		return "", fmt.Errorf("failed to load IMSI encryption keys: %w", err)
+	}
+
+	for _, k := range keys {
+		priv, err := imsicrypt.ParsePrivateKey(k.PrivateKey)
+		if err != nil {
+			return "", fmt.Errorf("invalid stored IMSI encryption key %q: %w", k.ID, err)
+		}
+		plain, err := imsicrypt.Decrypt(priv, enc.Ciphertext)
+		if err != nil {
+			continue
+		}
+		return h.permanentIMSI(plain, enc.Realm)
+	}
+	return "", fmt.Errorf("%w: encrypted identity does not decrypt under any IMSI encryption key", errInvalidIdentity)
+}
+
+// permanentIMSI returns the IMSI of a decrypted identity, a bare IMSI or a
+// permanent NAI. realm, the realm outside the encryption, applies when the
+// plaintext has none; a bare IMSI without either is used as is.
+func (h *Handler) permanentIMSI(plain, realm string) (string, error) {
+	if aka.IsDigits(plain, 1, 15) {
+		if realm == "" {
+			return plain, nil
+		}
+		plain = string(eapid.PrefixAKAPermanent) + plain
+	}
+	if realm != "" && !strings.Contains(plain, "@") {
+		plain += "@" + realm
+	}
+	nai, err := parseNAI(plain)
+	if err != nil {
+		return "", err
+	}
+	if nai.Type != eapid.Permanent {
+		return "", fmt.Errorf("%w: encrypted %s identity", errInvalidIdentity, nai.Type)
+	}
+	if err := h.checkRealm(nai, nai.IMSI); err != nil {
+		return "", err
+	}
+	return nai.IMSI, nil
+}
+
+// validIMSIEncryptionKeyID reports whether id can be carried in an encrypted
+// identity and in a URL path.
+func validIMSIEncryptionKeyID(id string) bool {
+	return id != "" && len(id) <= 128 && !strings.ContainsAny(id, ",@/ ")
+}
+
+// CreateIMSIEncryptionKey stores an RSA key pair. If private_key is omitted
+// a new 2048-bit key pair is generated. The response carries the public key
+// to provision on the handsets.
+func (h *Handler) CreateIMSIEncryptionKey(c *gin.Context) {
+	var k model.IMSIEncryptionKey
+	if err := c.ShouldBindJSON(&k); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !validIMSIEncryptionKeyID(k.ID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be 1 to 128 characters without ',', '@', '/' or spaces"})
+		return
+	}
+
+	var err error
+	if k.PrivateKey == "" {
+		k.PrivateKey, k.PublicKey, err = imsicrypt.GenerateKey(imsicrypt.MinKeyBits)
+	} else if priv, perr := imsicrypt.ParsePrivateKey(k.PrivateKey); perr != nil {
+		err = perr
+	} else {
+		k.PublicKey, err = imsicrypt.PublicKeyPEM(priv)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.Repo.CreateIMSIEncryptionKey(c.Request.Context(), &k); err != nil {
+		slog.Error("Failed to create IMSI encryption key", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create IMSI encryption key"})
+		return
+	}
+	k.PrivateKey = ""
+	c.JSON(http.StatusCreated, k)
+}
+
+func (h *Handler) GetIMSIEncryptionKey(c *gin.Context) {
+	k, err := h.Repo.GetIMSIEncryptionKey(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if k == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "IMSI encryption key not found"})
+		return
+	}
+	k.PrivateKey = ""
+	c.JSON(http.StatusOK, k)
+}
+
+func (h *Handler) ListIMSIEncryptionKeys(c *gin.Context) {
+	keys, err := h.Repo.ListIMSIEncryptionKeys(c.Request.Context())
+	if err != nil {
+		slog.Error("Failed to list IMSI encryption keys", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if keys == nil {
+		keys = []*model.IMSIEncryptionKey{}
+	}
+	for _, k := range keys {
+		k.PrivateKey = ""
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+func (h *Handler) DeleteIMSIEncryptionKey(c *gin.Context) {
+	if err := h.Repo.DeleteIMSIEncryptionKey(c.Request.Context(), c.Param("id")); err != nil {
+		slog.Error("Failed to delete IMSI encryption key", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete IMSI encryption key"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
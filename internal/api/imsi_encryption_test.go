@@ -0,0 +1,37 @@
+package api
+
+import (
+	"errors"
+	"testing"
+
+	"aka-server/internal/config"
+	"aka-server/internal/eapid"
+)
+
+func TestPermanentIMSI(t *testing.T) {
+	h := &Handler{Cfg: &config.Config{HomePLMNs: []string{"00101"}}}
+	const imsi = "001010123456789"
+	for _, tt := range []struct{ plain, realm string }{
+		{imsi, ""},
+		{"6" + imsi + "@wlan.mnc001.mcc001.3gppnetwork.org", ""},
+		{"0" + imsi, "wlan.mnc001.mcc001.3gppnetwork.org"},
+	} {
+		if got, err := h.permanentIMSI(tt.plain, tt.realm); err != nil || got != imsi {
+			t.Errorf("permanentIMSI(%q, %q) = %s, %v", tt.plain, tt.realm, got, err)
+		}
+	}
+
+	for _, tt := range []struct {
+		plain, realm string
+		want         error
+	}{
+		{"0" + imsi, "wlan.mnc410.mcc310.3gppnetwork.org", eapid.ErrForeignRealm},
+		{"0" + imsi, "", eapid.ErrMissingRealm},
+		{"2pseudonym", "", errInvalidIdentity},
+		{"garbage", "", eapid.ErrUnknownPrefix},
+	} {
+		if _, err := h.permanentIMSI(tt.plain, tt.realm); !errors.Is(err, tt.want) || !errors.Is(err, errInvalidIdentity) {
+			t.Errorf("permanentIMSI(%q, %q) = %v, want %v", tt.plain, tt.realm, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"aka-server/internal/aka"
+	"aka-server/internal/db"
+	"aka-server/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// validateOperatorProfile checks the constants and OP of p.
+func validateOperatorProfile(p *model.OperatorProfile) error {
+	if _, err := aka.MilenageConstantsFromProfile(p); err != nil {
+		return err
+	}
+	if op, err := hex.DecodeString(p.OP); err != nil || (len(op) != 0 && len(op) != 16 && len(op) != 32) {
+		return fmt.Errorf("invalid op: %q", p.OP)
+	}
+	return nil
+}
+
+// checkProfileOP checks that p's OP suits sub when sub derives its OPc from
+// it: 16 bytes for Milenage, a 32-byte TOP for TUAK.
+func checkProfileOP(sub *model.Subscriber, p *model.OperatorProfile) error {
+	if sub.Opc != "" || p == nil {
+		return nil
+	}
+	if p.OP == "" {
+		return fmt.Errorf("operator profile %q has no op for subscriber %s", p.ID, sub.IMSI)
+	}
+	if _, err := aka.ComputeOPc(sub.Algorithm, sub.Ki, p.OP); err != nil {
+		return fmt.Errorf("op of operator profile %q does not suit %s subscriber %s: %w", p.ID, sub.Algorithm, sub.IMSI, err)
+	}
+	return nil
+}
+
+// checkReferencingSubscribers checks p's OP against the subscribers that
+// reference p. Those with an OPc of their own pass.
+func checkReferencingSubscribers(subs []*model.Subscriber, p *model.OperatorProfile) error {
+	for _, sub := range subs {
+		if err := checkProfileOP(sub, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveOperator loads the operator profile referenced by sub into
+// sub.Operator. It is used when provisioning; vector generation gets the
+// profile from AdvanceSQN, which reads it inside the locked transaction.
+func (h *Handler) resolveOperator(ctx context.Context, sub *model.Subscriber) error {
+	if sub.OperatorProfile == "" {
+		return nil
+	}
+	p, err := h.Repo.GetOperatorProfile(ctx, sub.OperatorProfile)
+	if err != nil {
+		return fmt.Errorf("failed to load operator profile: %w", err)
+	}
+	if p == nil {
+		return fmt.Errorf("operator profile %q not found", sub.OperatorProfile)
+	}
+	sub.Operator = p
+	return nil
+}
+
+func (h *Handler) CreateOperatorProfile(c *gin.Context) {
+	var p model.OperatorProfile
+	if err := c.ShouldBindJSON(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if p.ID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id is required"})
+		return
+	}
+	if err := validateOperatorProfile(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.Repo.CreateOperatorProfile(c.Request.Context(), &p); err != nil {
+		slog.Error("Failed to create operator profile", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create operator profile"})
+		return
+	}
+	c.Status(http.StatusCreated)
+}
+
+func (h *Handler) GetOperatorProfile(c *gin.Context) {
+	p, err := h.Repo.GetOperatorProfile(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if p == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operator profile not found"})
+		return
+	}
+	p.OP = ""
+	c.JSON(http.StatusOK, p)
+}
+
+func (h *Handler) ListOperatorProfiles(c *gin.Context) {
+	profiles, err := h.Repo.ListOperatorProfiles(c.Request.Context())
+	if err != nil {
+		slog.Error("Failed to list operator profiles", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if profiles == nil {
+		profiles = []*model.OperatorProfile{}
+	}
+	for _, p := range profiles {
+		p.OP = ""
+	}
+	c.JSON(http.StatusOK, profiles)
+}
+
+func (h *Handler) UpdateOperatorProfile(c *gin.Context) {
+	var p model.OperatorProfile
+	if err := c.ShouldBindJSON(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p.ID = c.Param("id")
+	if err := validateOperatorProfile(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The referencing subscribers are checked inside the update transaction,
+	// so none can be added or re-pointed in between.
+	var checkErr error
+	err := h.Repo.UpdateOperatorProfile(c.Request.Context(), &p, func(subs []*model.Subscriber) error {
+		checkErr = checkReferencingSubscribers(subs, &p)
+		return checkErr
+	})
+	if checkErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": checkErr.Error()})
+		return
+	}
+	if errors.Is(err, db.ErrOperatorProfileNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operator profile not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update operator profile"})
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+func (h *Handler) DeleteOperatorProfile(c *gin.Context) {
+	if err := h.Repo.DeleteOperatorProfile(c.Request.Context(), c.Param("id")); err != nil {
+		slog.Error("Failed to delete operator profile", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete operator profile"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"aka-server/internal/model"
+	"aka-server/internal/suci"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DeconcealRequest struct {
+	SUCI string `json:"suci"`
+}
+
+// deconcealSUCI recovers the IMSI from s using the home network key named by
+// its key identifier.
+func (h *Handler) deconcealSUCI(ctx context.Context, s string) (string, error) {
+	u, err := suci.Parse(s)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errInvalidIdentity, err)
+	}
+	if u.Scheme == suci.SchemeNull {
+		return suci.Deconceal(u, nil)
+	}
+
+	profile, err := suci.ProfileForScheme(u.Scheme)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errInvalidIdentity, err)
+	}
+	key, err := h.Repo.GetHomeNetworkKey(ctx, u.KeyID)
+	if err != nil {
+		return "", fmt.Errorf("failed to load home network key: %w", err)
+	}
+	if key == nil || key.Profile != profile {
+		return "", fmt.Errorf("%w: no profile %s home network key with id %d", errInvalidIdentity, profile, u.KeyID)
+	}
+	priv, err := hex.DecodeString(key.PrivateKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid stored home network key %d: %w", key.ID, err)
+	}
+	imsi, err := suci.Deconceal(u, priv)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errInvalidIdentity, err)
+	}
+	return imsi, nil
+}
+
+// DeconcealSUCI is the SIDF endpoint: it returns the SUPI concealed in a
+// SUCI if the subscriber exists.
+func (h *Handler) DeconcealSUCI(c *gin.Context) {
+	var req DeconcealRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !suci.IsSUCI(req.SUCI) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "suci is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	imsi, err := h.deconcealSUCI(ctx, req.SUCI)
+	if err != nil {
+		slog.Warn("SUCI de-concealment failed", "error", err)
+		c.JSON(identityErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	sub, err := h.Repo.GetSubscriber(ctx, imsi)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if sub == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscriber not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"supi": "imsi-" + imsi, "imsi": imsi})
+}
+
+// homeNetworkKeyID parses the :id path parameter.
+func homeNetworkKeyID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id < 0 || id > 255 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be between 0 and 255"})
+		return 0, false
+	}
+	return id, true
+}
+
+// CreateHomeNetworkKey stores a home network key pair. If private_key is
+// omitted a new key pair is generated. The response carries the public key
+// to provision on the USIMs.
+func (h *Handler) CreateHomeNetworkKey(c *gin.Context) {
+	var k model.HomeNetworkKey
+	if err := c.ShouldBindJSON(&k); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if k.ID < 0 || k.ID > 255 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be between 0 and 255"})
+		return
+	}
+
+	var priv, pub []byte
+	var err error
+	if k.PrivateKey == "" {
+		priv, pub, err = suci.GenerateKey(k.Profile)
+	} else if priv, err = hex.DecodeString(k.PrivateKey); err == nil {
+		pub, err = suci.PublicKey(k.Profile, priv)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	k.PrivateKey = hex.EncodeToString(priv)
+	k.PublicKey = hex.EncodeToString(pub)
+
+	if err := h.Repo.CreateHomeNetworkKey(c.Request.Context(), &k); err != nil {
+		slog.Error("Failed to create home network key", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create home network key"})
+		return
+	}
+	k.PrivateKey = ""
+	c.JSON(http.StatusCreated, k)
+}
+
+func (h *Handler) GetHomeNetworkKey(c *gin.Context) {
+	id, ok := homeNetworkKeyID(c)
+	if !ok {
+		return
+	}
+	k, err := h.Repo.GetHomeNetworkKey(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if k == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Home network key not found"})
+		return
+	}
+	k.PrivateKey = ""
+	c.JSON(http.StatusOK, k)
+}
+
+func (h *Handler) ListHomeNetworkKeys(c *gin.Context) {
+	keys, err := h.Repo.ListHomeNetworkKeys(c.Request.Context())
+	if err != nil {
+		slog.Error("Failed to list home network keys", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if keys == nil {
+		keys = []*model.HomeNetworkKey{}
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+func (h *Handler) DeleteHomeNetworkKey(c *gin.Context) {
+	id, ok := homeNetworkKeyID(c)
+	if !ok {
+		return
+	}
+	if err := h.Repo.DeleteHomeNetworkKey(c.Request.Context(), id); err != nil {
+		slog.Error("Failed to delete home network key", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete home network key"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,202 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"aka-server/internal/config"
+	"aka-server/internal/db"
+	"aka-server/internal/model"
+	"aka-server/internal/suci"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TS 33.501 Annex C.4 test data. Both SUCIs conceal IMSI 20893001002086.
+const (
+	c4ProfileAPrivateKey = "c53c22208b61860b06c62e5406a7b330c2b577aa5558981510d128247d38bd1d"
+	c4ProfileAPublicKey  = "5a8d38864820197c3394b92613b20b91633cbd897119273bf8e4a6f4eec0a650"
+	c4ProfileASUCI       = "suci-0-208-93-0-1-1-b2e92f836055a255837debf850b528997ce0201cb82adfe4be1f587d07d8457dcb02352410cddd9e730ef3fa87"
+
+	c4ProfileBPrivateKey = "f1ab1074477ebcc7f554ea1c5fc368b1616730155e0041ac447d6301975fecda"
+	c4ProfileBPublicKey  = "0272da71976234ce833a6907425867b82e074d44ef907dfb4b3e21c1c2256ebcd1"
+	c4ProfileBSUCI       = "suci-0-208-93-0-2-2-039aab8376597021e855679a9778ea0b67396e68c66df32c0f41e9acca2da9b9d146a33fc2716ac7dae96aa30a4d"
+
+	c4IMSI = "20893001002086"
+)
+
+// c4Store serves a subscriber for the 14-digit Annex C.4 IMSI, which
+// checkSubscriber does not let us create.
+type c4Store struct {
+	*db.MemoryStore
+}
+
+func (s c4Store) GetSubscriber(ctx context.Context, imsi string) (*model.Subscriber, error) {
+	if imsi == c4IMSI {
+		return &model.Subscriber{IMSI: imsi}, nil
+	}
+	return s.MemoryStore.GetSubscriber(ctx, imsi)
+}
+
+// createC4Keys stores the Annex C.4 private keys as home network keys 1
+// (Profile A) and 2 (Profile B).
+func createC4Keys(t *testing.T, r *gin.Engine) {
+	t.Helper()
+	for _, k := range []struct {
+		id                 int
+		profile, priv, pub string
+	}{
+		{1, suci.ProfileA, c4ProfileAPrivateKey, c4ProfileAPublicKey},
+		{2, suci.ProfileB, c4ProfileBPrivateKey, c4ProfileBPublicKey},
+	} {
+		code, out := doJSON(r, http.MethodPost, "/api/v1/home-network-keys", map[string]any{
+			"id": k.id, "profile": k.profile, "private_key": k.priv,
+		})
+		if code != http.StatusCreated {
+			t.Fatalf("CreateHomeNetworkKey(%d) returned %d %v", k.id, code, out)
+		}
+		if out["public_key"] != k.pub || out["private_key"] != nil {
+			t.Errorf("CreateHomeNetworkKey(%d) returned %v", k.id, out)
+		}
+	}
+}
+
+func TestHomeNetworkKeys(t *testing.T) {
+	r := newTestRouter(t)
+	createC4Keys(t, r)
+
+	code, out := doJSON(r, http.MethodPost, "/api/v1/home-network-keys", map[string]any{"id": 3, "profile": suci.ProfileB})
+	if code != http.StatusCreated || len(out["public_key"].(string)) != 66 || out["private_key"] != nil {
+		t.Fatalf("Generating a key returned %d %v", code, out)
+	}
+	for _, body := range []map[string]any{
+		{"id": 4, "profile": "C"},
+		{"id": 256, "profile": suci.ProfileA},
+		{"id": 4, "profile": suci.ProfileA, "private_key": "zz"},
+	} {
+		if code, _ := doJSON(r, http.MethodPost, "/api/v1/home-network-keys", body); code != http.StatusBadRequest {
+			t.Errorf("CreateHomeNetworkKey(%v) returned %d", body, code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/home-network-keys", nil))
+	var keys []map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &keys); w.Code != http.StatusOK || err != nil {
+		t.Fatalf("ListHomeNetworkKeys returned %d %v", w.Code, err)
+	}
+	if len(keys) != 3 {
+		t.Fatalf("Expected 3 keys, got %v", keys)
+	}
+	for _, k := range keys {
+		if k["private_key"] != nil {
+			t.Errorf("ListHomeNetworkKeys exposed a private key: %v", k)
+		}
+	}
+
+	code, out = doJSON(r, http.MethodGet, "/api/v1/home-network-keys/1", nil)
+	if code != http.StatusOK || out["public_key"] != c4ProfileAPublicKey || out["private_key"] != nil {
+		t.Errorf("GetHomeNetworkKey returned %d %v", code, out)
+	}
+	if code, _ := doJSON(r, http.MethodDelete, "/api/v1/home-network-keys/1", nil); code != http.StatusNoContent {
+		t.Errorf("DeleteHomeNetworkKey returned %d", code)
+	}
+	if code, _ := doJSON(r, http.MethodGet, "/api/v1/home-network-keys/1", nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 after delete, got %d", code)
+	}
+	if code, _ := doJSON(r, http.MethodGet, "/api/v1/home-network-keys/x", nil); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad id, got %d", code)
+	}
+}
+
+func TestDeconcealSUCIEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	NewHandler(c4Store{db.NewMemoryStore()}, nil, &config.Config{}).RegisterRoutes(r)
+	createC4Keys(t, r)
+
+	for _, s := range []string{c4ProfileASUCI, c4ProfileBSUCI} {
+		code, out := doJSON(r, http.MethodPost, "/api/v1/suci/deconceal", map[string]string{"suci": s})
+		if code != http.StatusOK || out["imsi"] != c4IMSI || out["supi"] != "imsi-"+c4IMSI {
+			t.Errorf("DeconcealSUCI(%s) returned %d %v", s, code, out)
+		}
+	}
+
+	// Flipping the last nibble breaks the MAC tag.
+	tampered := c4ProfileASUCI[:len(c4ProfileASUCI)-1] + "8"
+	for name, s := range map[string]string{
+		"unknown key id": strings.Replace(c4ProfileASUCI, "-1-1-", "-1-9-", 1),
+		"wrong profile":  strings.Replace(c4ProfileASUCI, "-1-1-", "-1-2-", 1),
+		"MAC failure":    tampered,
+		"not a SUCI":     c4IMSI,
+	} {
+		if code, out := doJSON(r, http.MethodPost, "/api/v1/suci/deconceal", map[string]string{"suci": s}); code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d %v", name, code, out)
+		}
+	}
+
+	// The null scheme needs no key; this subscriber does not exist.
+	if code, _ := doJSON(r, http.MethodPost, "/api/v1/suci/deconceal", map[string]string{"suci": "suci-0-001-01-0-0-0-0123456789"}); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown subscriber, got %d", code)
+	}
+
+	if code, _ := doJSON(r, http.MethodDelete, "/api/v1/home-network-keys/1", nil); code != http.StatusNoContent {
+		t.Fatalf("DeleteHomeNetworkKey returned %d", code)
+	}
+	if code, _ := doJSON(r, http.MethodPost, "/api/v1/suci/deconceal", map[string]string{"suci": c4ProfileASUCI}); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 after the key was deleted, got %d", code)
+	}
+}
+
+func TestAuthVectorSUCI(t *testing.T) {
+	r := newTestRouter(t)
+	createC4Keys(t, r)
+	const imsi, other = "001010123456789", "001010123456780"
+	for _, id := range []string{imsi, other} {
+		doJSON(r, http.MethodPost, "/api/v1/subscribers", map[string]string{
+			"imsi": id,
+			"ki":   "465b5ce8b199b49faa5f0a2ee238a6bc",
+			"opc":  "cd63cb71954a9f4e48a5994e37a02baf",
+			"sqn":  "000000000020",
+			"amf":  "8000",
+		})
+	}
+
+	for _, tc := range []struct {
+		scheme, keyID int
+		pub           string
+	}{
+		{suci.SchemeProfileA, 1, c4ProfileAPublicKey},
+		{suci.SchemeProfileB, 2, c4ProfileBPublicKey},
+	} {
+		pub, _ := hex.DecodeString(tc.pub)
+		u, err := suci.Conceal(imsi, 2, "0", tc.scheme, tc.keyID, pub)
+		if err != nil {
+			t.Fatalf("Conceal failed: %v", err)
+		}
+		code, out := doJSON(r, http.MethodPost, "/api/v1/auth/"+u.String(), map[string]string{})
+		if code != http.StatusOK || out["rand"] == nil {
+			t.Errorf("GenerateAuthVector(scheme %d) returned %d %v", tc.scheme, code, out)
+		}
+
+		u.KeyID = 9
+		if code, _ := doJSON(r, http.MethodPost, "/api/v1/auth/"+u.String(), map[string]string{}); code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for an unknown key id, got %d", code)
+		}
+	}
+
+	for id, want := range map[string]int{imsi: 2, other: 0} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/subscribers/"+id+"/auth-events", nil))
+		var events []map[string]any
+		_ = json.Unmarshal(w.Body.Bytes(), &events)
+		if w.Code != http.StatusOK || len(events) != want {
+			t.Errorf("%s: expected %d auth events, got %d %v", id, want, w.Code, events)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Package authctx keeps pending authentication contexts between vector
+// issuance and RES confirmation.
+package authctx
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"aka-server/internal/gba"
+)
+
+// Context is one issued vector waiting for the UE's RES.
+type Context struct {
+	ID     string
+	IMSI   string
+	Method string
+	Rand   string
+	// XRES is the expected response: XRES, or XRES* for 5G-AKA.
+	XRES string
+	// Kausf and Kseaf are withheld from the 5G-AKA response until the
+	// RES* is confirmed.
+	Kausf string
+	Kseaf string
+	// Withheld holds further response fields, such as the EAP MSK and
+	// EMSK, that are released with a successful confirmation.
+	Withheld map[string]string
+	// Bootstrap is the GBA bootstrapping context of the run. It becomes
+	// usable under its B-TID only once the RES is confirmed (TS 33.220
+	// 4.5.2).
+	Bootstrap *gba.Context
+	Expires   time.Time
+}
+
+// Store is an in-memory set of pending contexts that expire after a fixed
+// lifetime.
+type Store struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	items map[string]*Context
+	// Now returns the current time. Tests may replace it.
+	Now func() time.Time
+}
+
+// NewStore returns a Store whose contexts live for ttl.
+func NewStore(ttl time.Duration) *Store {
+	return &Store{ttl: ttl, items: make(map[string]*Context), Now: time.Now}
+}
+
+// Add assigns c a random ID and an expiry time and stores it.
+func (s *Store) Add(c *Context) error {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.Now()
+	for id, old := range s.items {
+		if now.After(old.Expires) {
+			delete(s.items, id)
+		}
+	}
+	c.ID = hex.EncodeToString(b[:])
+	c.Expires = now.Add(s.ttl)
+	s.items[c.ID] = c
+	return nil
+}
+
+// Take removes and returns the context with the given ID issued to imsi. It
+// returns nil if there is none or it has expired. A context can be taken
+// only once, so each vector allows a single confirmation attempt.
+func (s *Store) Take(id, imsi string) *Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.items[id]
+	if !ok || c.IMSI != imsi {
+		return nil
+	}
+	delete(s.items, id)
+	if s.Now().After(c.Expires) {
+		return nil
+	}
+	return c
+}
@@ -0,0 +1,42 @@
+package authctx
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	now := time.Unix(1000, 0)
+	s := NewStore(30 * time.Second)
+	s.Now = func() time.Time { return now }
+
+	a := &Context{IMSI: "001010000000001", XRES: "a54211d5e3ba50bf"}
+	b := &Context{IMSI: "001010000000001", XRES: "a54211d5e3ba50bf"}
+	if err := s.Add(a); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := s.Add(b); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if a.ID == "" || a.ID == b.ID {
+		t.Fatalf("Expected distinct IDs, got %q and %q", a.ID, b.ID)
+	}
+
+	if got := s.Take(a.ID, "001010000000002"); got != nil {
+		t.Error("Expected no context for another IMSI")
+	}
+	if got := s.Take(a.ID, a.IMSI); got != a {
+		t.Errorf("Take returned %v, want %v", got, a)
+	}
+	if got := s.Take(a.ID, a.IMSI); got != nil {
+		t.Error("Expected a context to be taken only once")
+	}
+
+	now = now.Add(31 * time.Second)
+	if got := s.Take(b.ID, b.IMSI); got != nil {
+		t.Error("Expected an expired context to be gone")
+	}
+	if len(s.items) != 0 {
+		t.Errorf("Expected empty store, got %d items", len(s.items))
+	}
+}
@@ -1,45 +1,93 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	DBHost            string
-	DBPort            string
-	DBUser            string
-	DBPassword        string
-	DBName            string
-	APIPort           string
-	AuthAPIAllowedIPs []string
-	DBAPIAllowedIPs   []string
-	LogFile           string
-	LogMaxSize        int
-	LogMaxBackups     int
-	LogMaxAge         int
+	DBBackend          string
+	DBPath             string
+	DBHost             string
+	DBPort             string
+	DBUser             string
+	DBPassword         string
+	DBName             string
+	APIPort            string
+	AuthAPIAllowedIPs  []string
+	DBAPIAllowedIPs    []string
+	AuthMaxVectors     int
+	AuthContextTTL     time.Duration
+	AuthRequireConfirm bool
+	AuthResyncWindow   time.Duration
+	AuthEventRetention time.Duration
+	HomePLMNs          []string
+	AKMAKeyLifetime    time.Duration
+	GBAKeyLifetime     time.Duration
+	GBABSFName         string
+	EAPIdentityKey     string
+	EAPReauthLifetime  time.Duration
+	SQNINDBits         int
+	SQNINDAllocation   string
+	SQNDelta           int
+	SQNResyncMaxAhead  int
+	SQNWrapMargin      int
+	SQNTimeGranularity time.Duration
+	DebugRandSeed      string
+	LogFile            string
+	LogMaxSize         int
+	LogMaxBackups      int
+	LogMaxAge          int
 }
 
 func LoadConfig() (*Config, error) {
 	// Load .env file if it exists, but don't fail if it doesn't (might be env vars)
 	_ = godotenv.Load()
 
+	// Malformed durations are reported rather than replaced by the default.
+	var errs []error
 	cfg := &Config{
-		DBHost:            getEnv("DB_HOST", "localhost"),
-		DBPort:            getEnv("DB_PORT", "5432"),
-		DBUser:            getEnv("DB_USER", "akaserver"),
-		DBPassword:        getEnv("DB_PASSWORD", "akaserver"),
-		DBName:            getEnv("DB_NAME", "akaserverdb"),
-		APIPort:           getEnv("API_PORT", "8080"),
-		AuthAPIAllowedIPs: getEnvAsSlice("AUTH_API_ALLOWED_IPS"),
-		DBAPIAllowedIPs:   getEnvAsSlice("DB_API_ALLOWED_IPS"),
-		LogFile:           getEnv("LOG_FILE", "akaserver.log"),
-		LogMaxSize:        getEnvAsInt("LOG_MAX_SIZE", 10),
-		LogMaxBackups:     getEnvAsInt("LOG_MAX_BACKUPS", 3),
-		LogMaxAge:         getEnvAsInt("LOG_MAX_AGE", 28),
+		DBBackend:          getEnv("DB_BACKEND", "postgres"),
+		DBPath:             getEnv("DB_PATH", "akaserver.db"),
+		DBHost:             getEnv("DB_HOST", "localhost"),
+		DBPort:             getEnv("DB_PORT", "5432"),
+		DBUser:             getEnv("DB_USER", "akaserver"),
+		DBPassword:         getEnv("DB_PASSWORD", "akaserver"),
+		DBName:             getEnv("DB_NAME", "akaserverdb"),
+		APIPort:            getEnv("API_PORT", "8080"),
+		AuthAPIAllowedIPs:  getEnvAsSlice("AUTH_API_ALLOWED_IPS"),
+		DBAPIAllowedIPs:    getEnvAsSlice("DB_API_ALLOWED_IPS"),
+		AuthMaxVectors:     getEnvAsInt("AUTH_MAX_VECTORS", 5),
+		AuthContextTTL:     getEnvAsDuration("AUTH_CONTEXT_TTL", 30*time.Second, &errs),
+		AuthRequireConfirm: getEnvAsBool("AUTH_REQUIRE_CONFIRM", false),
+		AuthResyncWindow:   getEnvAsDuration("AUTH_RESYNC_WINDOW", 24*time.Hour, &errs),
+		AuthEventRetention: getEnvAsDuration("AUTH_EVENT_RETENTION", 30*24*time.Hour, &errs),
+		HomePLMNs:          getEnvAsSlice("HOME_PLMNS"),
+		AKMAKeyLifetime:    getEnvAsDuration("AKMA_KEY_LIFETIME", 0, &errs),
+		GBAKeyLifetime:     getEnvAsDuration("GBA_KEY_LIFETIME", time.Hour, &errs),
+		GBABSFName:         getEnv("GBA_BSF_NAME", ""),
+		EAPIdentityKey:     getEnv("EAP_IDENTITY_KEY", ""),
+		EAPReauthLifetime:  getEnvAsDuration("EAP_REAUTH_LIFETIME", time.Hour, &errs),
+		SQNINDBits:         getEnvAsInt("SQN_IND_BITS", 5),
+		SQNINDAllocation:   getEnv("SQN_IND_ALLOCATION", "fixed"),
+		SQNDelta:           getEnvAsInt("SQN_DELTA", 1<<28),
+		SQNResyncMaxAhead:  getEnvAsInt("SQN_RESYNC_MAX_AHEAD", 1<<28),
+		SQNWrapMargin:      getEnvAsInt("SQN_WRAP_MARGIN", 0),
+		SQNTimeGranularity: getEnvAsDuration("SQN_TIME_GRANULARITY", time.Second, &errs),
+		DebugRandSeed:      getEnv("DEBUG_RAND_SEED", ""),
+		LogFile:            getEnv("LOG_FILE", "akaserver.log"),
+		LogMaxSize:         getEnvAsInt("LOG_MAX_SIZE", 10),
+		LogMaxBackups:      getEnvAsInt("LOG_MAX_BACKUPS", 3),
+		LogMaxAge:          getEnvAsInt("LOG_MAX_AGE", 28),
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
 	}
 
 	return cfg, nil
@@ -75,3 +123,28 @@ func getEnvAsInt(key string, fallback int) int {
 	}
 	return value
 }
+
+func getEnvAsBool(key string, fallback bool) bool {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return fallback
+	}
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func getEnvAsDuration(key string, fallback time.Duration, errs *[]error) time.Duration {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return fallback
+	}
+	value, err := time.ParseDuration(valueStr)
+	if err != nil {
+		*errs = append(*errs, fmt.Errorf("invalid %s: %w", key, err))
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"aka-server/internal/model"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func insertAuthEvents(ctx context.Context, tx pgx.Tx, events []*model.AuthEvent) error {
+	query := `
+		INSERT INTO public.auth_events (imsi, rand, sqn, ind, method, client, node_id, resync)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	for _, e := range events {
+		if _, err := tx.Exec(ctx, query, e.IMSI, e.Rand, e.SQN, e.IND, e.Method, e.Client, e.NodeID, e.Resync); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RandIssued reports whether rand was issued to imsi at or after since.
+func (r *Repository) RandIssued(ctx context.Context, imsi, rand string, since time.Time) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM public.auth_events
+			WHERE imsi = $1 AND rand = lower($2) AND issued_at >= $3
+		)
+	`
+	var ok bool
+	err := r.Pool.QueryRow(ctx, query, imsi, rand, since).Scan(&ok)
+	return ok, err
+}
+
+// ListAuthEvents returns the latest limit auth events of imsi, newest first.
+func (r *Repository) ListAuthEvents(ctx context.Context, imsi string, limit int) ([]*model.AuthEvent, error) {
+	query := `
+		SELECT id, imsi, rand, sqn, ind, method, client, node_id, resync, issued_at
+		FROM public.auth_events
+		WHERE imsi = $1
+		ORDER BY issued_at DESC, id DESC
+		LIMIT $2
+	`
+	rows, err := r.Pool.Query(ctx, query, imsi, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*model.AuthEvent
+	for rows.Next() {
+		var e model.AuthEvent
+		if err := rows.Scan(&e.ID, &e.IMSI, &e.Rand, &e.SQN, &e.IND, &e.Method, &e.Client, &e.NodeID, &e.Resync, &e.IssuedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, &e)
+	}
+	return events, rows.Err()
+}
+
+// DeleteAuthEventsBefore removes auth events issued before before and returns
+// how many were removed.
+func (r *Repository) DeleteAuthEventsBefore(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.Pool.Exec(ctx, `DELETE FROM public.auth_events WHERE issued_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+
+	"aka-server/internal/model"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateHomeNetworkKey inserts k and sets k.CreatedAt.
+func (r *Repository) CreateHomeNetworkKey(ctx context.Context, k *model.HomeNetworkKey) error {
+	query := `
+		INSERT INTO public.home_network_keys (id, profile, private_key, public_key)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at
+	`
+	return r.Pool.QueryRow(ctx, query, k.ID, k.Profile, k.PrivateKey, k.PublicKey).Scan(&k.CreatedAt)
+}
+
+// GetHomeNetworkKey returns nil, nil if the key does not exist.
+func (r *Repository) GetHomeNetworkKey(ctx context.Context, id int) (*model.HomeNetworkKey, error) {
+	query := `SELECT id, profile, private_key, public_key, created_at FROM public.home_network_keys WHERE id = $1`
+	var k model.HomeNetworkKey
+	err := r.Pool.QueryRow(ctx, query, id).Scan(&k.ID, &k.Profile, &k.PrivateKey, &k.PublicKey, &k.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// ListHomeNetworkKeys returns all keys without their private halves.
+func (r *Repository) ListHomeNetworkKeys(ctx context.Context) ([]*model.HomeNetworkKey, error) {
+	query := `SELECT id, profile, public_key, created_at FROM public.home_network_keys ORDER BY id`
+	rows, err := r.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*model.HomeNetworkKey
+	for rows.Next() {
+		var k model.HomeNetworkKey
+		if err := rows.Scan(&k.ID, &k.Profile, &k.PublicKey, &k.CreatedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, &k)
+	}
+	return keys, rows.Err()
+}
+
+func (r *Repository) DeleteHomeNetworkKey(ctx context.Context, id int) error {
+	query := `DELETE FROM public.home_network_keys WHERE id = $1`
+	_, err := r.Pool.Exec(ctx, query, id)
+	return err
+}
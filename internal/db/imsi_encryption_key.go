@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+
+	"aka-server/internal/model"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateIMSIEncryptionKey inserts k and sets k.CreatedAt.
+func (r *Repository) CreateIMSIEncryptionKey(ctx context.Context, k *model.IMSIEncryptionKey) error {
+	query := `
+		INSERT INTO public.imsi_encryption_keys (id, private_key, public_key)
+		VALUES ($1, $2, $3)
+		RETURNING created_at
+	`
+	return r.Pool.QueryRow(ctx, query, k.ID, k.PrivateKey, k.PublicKey).Scan(&k.CreatedAt)
+}
+
+// GetIMSIEncryptionKey returns nil, nil if the key does not exist.
+func (r *Repository) GetIMSIEncryptionKey(ctx context.Context, id string) (*model.IMSIEncryptionKey, error) {
+	query := `SELECT id, private_key, public_key, created_at FROM public.imsi_encryption_keys WHERE id = $1`
+	var k model.IMSIEncryptionKey
+	err := r.Pool.QueryRow(ctx, query, id).Scan(&k.ID, &k.PrivateKey, &k.PublicKey, &k.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// ListIMSIEncryptionKeys returns all keys, newest first, including their
+// private halves: identities that do not name a key are tried against each.
+func (r *Repository) ListIMSIEncryptionKeys(ctx context.Context) ([]*model.IMSIEncryptionKey, error) {
+	query := `SELECT id, private_key, public_key, created_at FROM public.imsi_encryption_keys ORDER BY created_at DESC, id`
+	rows, err := r.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*model.IMSIEncryptionKey
+	for rows.Next() {
+		var k model.IMSIEncryptionKey
+		if err := rows.Scan(&k.ID, &k.PrivateKey, &k.PublicKey, &k.CreatedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, &k)
+	}
+	return keys, rows.Err()
+}
+
+func (r *Repository) DeleteIMSIEncryptionKey(ctx context.Context, id string) error {
+	query := `DELETE FROM public.imsi_encryption_keys WHERE id = $1`
+	_, err := r.Pool.Exec(ctx, query, id)
+	return err
+}
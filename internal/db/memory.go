@@ -0,0 +1,408 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"aka-server/internal/model"
+)
+
+// MemoryStore is a Store that keeps everything in process memory. It is
+// meant for tests and throwaway lab setups: nothing survives a restart.
+// It enforces the same keys and references as the SQL schema.
+type MemoryStore struct {
+	mu              sync.Mutex
+	subscribers     map[string]*model.Subscriber
+	profiles        map[string]*model.OperatorProfile
+	events          []*model.AuthEvent
+	lastEventID     int64
+	homeNetworkKeys map[int]*model.HomeNetworkKey
+	imsiKeys        map[string]*model.IMSIEncryptionKey
+
+	// Now returns the current time. Tests may replace it.
+	Now func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		subscribers:     make(map[string]*model.Subscriber),
+		profiles:        make(map[string]*model.OperatorProfile),
+		homeNetworkKeys: make(map[int]*model.HomeNetworkKey),
+		imsiKeys:        make(map[string]*model.IMSIEncryptionKey),
+		Now:             time.Now,
+	}
+}
+
+func (s *MemoryStore) Close() {}
+
+// copySubscriber returns the stored columns of sub.
+func copySubscriber(sub *model.Subscriber) *model.Subscriber {
+	c := *sub
+	c.OP, c.Operator = "", nil
+	return &c
+}
+
+func copyOperatorProfile(p *model.OperatorProfile) *model.OperatorProfile {
+	c := *p
+	for _, r := range []**int{&c.R1, &c.R2, &c.R3, &c.R4, &c.R5} {
+		if *r != nil {
+			v := **r
+			*r = &v
+		}
+	}
+	return &c
+}
+
+// checkOperatorProfile enforces the operator_profile foreign key and checks
+// the profile's OP like Repository.CreateSubscriber.
+func (s *MemoryStore) checkOperatorProfile(sub *model.Subscriber) error {
+	if sub.OperatorProfile == "" {
+		return nil
+	}
+	p := s.profiles[sub.OperatorProfile]
+	if p == nil {
+		return fmt.Errorf("operator profile %q does not exist", sub.OperatorProfile)
+	}
+	return checkProfileOPUnchanged(sub, p.OP)
+}
+
+func (s *MemoryStore) CreateSubscriber(ctx context.Context, sub *model.Subscriber) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subscribers[sub.IMSI] != nil {
+		return fmt.Errorf("subscriber %s already exists", sub.IMSI)
+	}
+	if err := checkSubscriber(sub); err != nil {
+		return err
+	}
+	if err := s.checkOperatorProfile(sub); err != nil {
+		return err
+	}
+	c := copySubscriber(sub)
+	c.CreatedAt = s.Now()
+	s.subscribers[sub.IMSI] = c
+	return nil
+}
+
+func (s *MemoryStore) GetSubscriber(ctx context.Context, imsi string) (*model.Subscriber, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub := s.subscribers[imsi]
+	if sub == nil {
+		return nil, nil
+	}
+	return copySubscriber(sub), nil
+}
+
+func (s *MemoryStore) UpdateSubscriber(ctx context.Context, sub *model.Subscriber) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old := s.subscribers[sub.IMSI]
+	if old == nil {
+		return nil
+	}
+	if err := checkSubscriber(sub); err != nil {
+		return err
+	}
+	if err := s.checkOperatorProfile(sub); err != nil {
+		return err
+	}
+	c := copySubscriber(sub)
+	c.CreatedAt = old.CreatedAt
+	s.subscribers[sub.IMSI] = c
+	return nil
+}
+
+// DeleteSubscriber also deletes the subscriber's auth events.
+func (s *MemoryStore) DeleteSubscriber(ctx context.Context, imsi string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subscribers, imsi)
+	events := s.events[:0]
+	for _, e := range s.events {
+		if e.IMSI != imsi {
+			events = append(events, e)
+		}
+	}
+	s.events = events
+	return nil
+}
+
+func (s *MemoryStore) UpdateSQN(ctx context.Context, imsi, newSQN string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub := s.subscribers[imsi]
+	if sub == nil {
+		return nil
+	}
+	if !sqnPattern.MatchString(newSQN) {
+		return errors.New("sqn must be 6 bytes of hex")
+	}
+	sub.SQN = newSQN
+	return nil
+}
+
+// AdvanceSQN holds the store lock while fn runs, so calls are serialised
+// across all subscribers.
+func (s *MemoryStore) AdvanceSQN(ctx context.Context, imsi string, fn func(sub *model.Subscriber) (string, []*model.AuthEvent, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := s.subscribers[imsi]
+	if stored == nil {
+		return ErrSubscriberNotFound
+	}
+	sub := copySubscriber(stored)
+	if sub.OperatorProfile != "" {
+		p := s.profiles[sub.OperatorProfile]
+		if p == nil {
+			return fmt.Errorf("failed to load operator profile %q: not found", sub.OperatorProfile)
+		}
+		sub.Operator = copyOperatorProfile(p)
+	}
+
+	newSQN, events, err := fn(sub)
+	if err != nil {
+		return err
+	}
+	if !sqnPattern.MatchString(newSQN) {
+		return errors.New("sqn must be 6 bytes of hex")
+	}
+	stored.SQN = newSQN
+	now := s.Now()
+	for _, e := range events {
+		c := *e
+		s.lastEventID++
+		c.ID, c.IssuedAt = s.lastEventID, now
+		s.events = append(s.events, &c)
+	}
+	return nil
+}
+
+func (s *MemoryStore) GetSubscriberCount(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int64(len(s.subscribers)), nil
+}
+
+func (s *MemoryStore) ListSubscribers(ctx context.Context) ([]*model.Subscriber, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var subs []*model.Subscriber
+	for _, sub := range s.subscribers {
+		subs = append(subs, copySubscriber(sub))
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].IMSI < subs[j].IMSI })
+	return subs, nil
+}
+
+func (s *MemoryStore) RandIssued(ctx context.Context, imsi, rand string, since time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rand = strings.ToLower(rand)
+	for _, e := range s.events {
+		if e.IMSI == imsi && e.Rand == rand && !e.IssuedAt.Before(since) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *MemoryStore) ListAuthEvents(ctx context.Context, imsi string, limit int) ([]*model.AuthEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var events []*model.AuthEvent
+	// Events are appended in ID order, so walking backwards is newest first.
+	for i := len(s.events) - 1; i >= 0 && len(events) < limit; i-- {
+		if e := s.events[i]; e.IMSI == imsi {
+			c := *e
+			events = append(events, &c)
+		}
+	}
+	return events, nil
+}
+
+func (s *MemoryStore) DeleteAuthEventsBefore(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.events[:0]
+	for _, e := range s.events {
+		if !e.IssuedAt.Before(before) {
+			kept = append(kept, e)
+		}
+	}
+	n := int64(len(s.events) - len(kept))
+	clear(s.events[len(kept):])
+	s.events = kept
+	return n, nil
+}
+
+func (s *MemoryStore) CreateOperatorProfile(ctx context.Context, p *model.OperatorProfile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.profiles[p.ID] != nil {
+		return fmt.Errorf("operator profile %q already exists", p.ID)
+	}
+	c := copyOperatorProfile(p)
+	c.CreatedAt = s.Now()
+	s.profiles[p.ID] = c
+	return nil
+}
+
+func (s *MemoryStore) GetOperatorProfile(ctx context.Context, id string) (*model.OperatorProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.profiles[id]
+	if p == nil {
+		return nil, nil
+	}
+	return copyOperatorProfile(p), nil
+}
+
+func (s *MemoryStore) ListOperatorProfiles(ctx context.Context) ([]*model.OperatorProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var profiles []*model.OperatorProfile
+	for _, p := range s.profiles {
+		profiles = append(profiles, copyOperatorProfile(p))
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].ID < profiles[j].ID })
+	return profiles, nil
+}
+
+// UpdateOperatorProfile behaves like Repository.UpdateOperatorProfile.
+func (s *MemoryStore) UpdateOperatorProfile(ctx context.Context, p *model.OperatorProfile, check func(subs []*model.Subscriber) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old := s.profiles[p.ID]
+	if old == nil {
+		return ErrOperatorProfileNotFound
+	}
+	if check != nil {
+		var subs []*model.Subscriber
+		for _, sub := range s.subscribers {
+			if sub.OperatorProfile == p.ID {
+				subs = append(subs, copySubscriber(sub))
+			}
+		}
+		if err := check(subs); err != nil {
+			return err
+		}
+	}
+	c := copyOperatorProfile(p)
+	c.CreatedAt = old.CreatedAt
+	s.profiles[p.ID] = c
+	return nil
+}
+
+// DeleteOperatorProfile fails while subscribers still reference the profile.
+func (s *MemoryStore) DeleteOperatorProfile(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sub := range s.subscribers {
+		if sub.OperatorProfile == id {
+			return fmt.Errorf("operator profile %q is referenced by subscriber %s", id, sub.IMSI)
+		}
+	}
+	delete(s.profiles, id)
+	return nil
+}
+
+// CreateHomeNetworkKey sets k.CreatedAt.
+func (s *MemoryStore) CreateHomeNetworkKey(ctx context.Context, k *model.HomeNetworkKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.homeNetworkKeys[k.ID] != nil {
+		return fmt.Errorf("home network key %d already exists", k.ID)
+	}
+	k.CreatedAt = s.Now()
+	c := *k
+	s.homeNetworkKeys[k.ID] = &c
+	return nil
+}
+
+func (s *MemoryStore) GetHomeNetworkKey(ctx context.Context, id int) (*model.HomeNetworkKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := s.homeNetworkKeys[id]
+	if k == nil {
+		return nil, nil
+	}
+	c := *k
+	return &c, nil
+}
+
+// ListHomeNetworkKeys returns all keys without their private halves.
+func (s *MemoryStore) ListHomeNetworkKeys(ctx context.Context) ([]*model.HomeNetworkKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keys []*model.HomeNetworkKey
+	for _, k := range s.homeNetworkKeys {
+		c := *k
+		c.PrivateKey = ""
+		keys = append(keys, &c)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys, nil
+}
+
+func (s *MemoryStore) DeleteHomeNetworkKey(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.homeNetworkKeys, id)
+	return nil
+}
+
+// CreateIMSIEncryptionKey sets k.CreatedAt.
+func (s *MemoryStore) CreateIMSIEncryptionKey(ctx context.Context, k *model.IMSIEncryptionKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.imsiKeys[k.ID] != nil {
+		return fmt.Errorf("IMSI encryption key %q already exists", k.ID)
+	}
+	k.CreatedAt = s.Now()
+	c := *k
+	s.imsiKeys[k.ID] = &c
+	return nil
+}
+
+func (s *MemoryStore) GetIMSIEncryptionKey(ctx context.Context, id string) (*model.IMSIEncryptionKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := s.imsiKeys[id]
+	if k == nil {
+		return nil, nil
+	}
+	c := *k
+	return &c, nil
+}
+
+// ListIMSIEncryptionKeys returns all keys, newest first, including their
+// private halves.
+func (s *MemoryStore) ListIMSIEncryptionKeys(ctx context.Context) ([]*model.IMSIEncryptionKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keys []*model.IMSIEncryptionKey
+	for _, k := range s.imsiKeys {
+		c := *k
+		keys = append(keys, &c)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.After(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys, nil
+}
+
+func (s *MemoryStore) DeleteIMSIEncryptionKey(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.imsiKeys, id)
+	return nil
+}
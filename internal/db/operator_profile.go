@@ -0,0 +1,165 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"aka-server/internal/model"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrOperatorProfileNotFound is returned by operations that require an
+// existing operator profile row.
+var ErrOperatorProfileNotFound = errors.New("operator profile not found")
+
+// ErrOperatorProfileChanged is returned when a subscriber without an OPc is
+// written while the OP of its operator profile differs from sub.Operator,
+// the copy the subscriber was validated against.
+var ErrOperatorProfileChanged = errors.New("operator profile changed")
+
+// checkProfileOPUnchanged compares the current OP of sub's operator profile
+// with sub.Operator. Subscribers with an OPc, or written without a validated
+// profile, do not depend on it.
+func checkProfileOPUnchanged(sub *model.Subscriber, op string) error {
+	if sub.Opc == "" && sub.Operator != nil && sub.Operator.OP != op {
+		return fmt.Errorf("%w: %q", ErrOperatorProfileChanged, sub.OperatorProfile)
+	}
+	return nil
+}
+
+// lockProfileOP share-locks the operator profile of sub for the rest of tx
+// and checks that its OP is still the one sub was validated against. The
+// lock conflicts with UpdateOperatorProfile.
+func lockProfileOP(ctx context.Context, tx pgx.Tx, sub *model.Subscriber) error {
+	if sub.OperatorProfile == "" {
+		return nil
+	}
+	var op string
+	err := tx.QueryRow(ctx, `SELECT op FROM public.operator_profiles WHERE id = $1 FOR SHARE`, sub.OperatorProfile).Scan(&op)
+	if err == pgx.ErrNoRows {
+		// The foreign key rejects the write.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return checkProfileOPUnchanged(sub, op)
+}
+
+const operatorProfileColumns = `id, op, c1, c2, c3, c4, c5, r1, r2, r3, r4, r5, created_at`
+
+func scanOperatorProfile(row pgx.Row) (*model.OperatorProfile, error) {
+	var p model.OperatorProfile
+	err := row.Scan(&p.ID, &p.OP, &p.C1, &p.C2, &p.C3, &p.C4, &p.C5, &p.R1, &p.R2, &p.R3, &p.R4, &p.R5, &p.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *Repository) CreateOperatorProfile(ctx context.Context, p *model.OperatorProfile) error {
+	query := `
+		INSERT INTO public.operator_profiles (id, op, c1, c2, c3, c4, c5, r1, r2, r3, r4, r5)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+	_, err := r.Pool.Exec(ctx, query, p.ID, p.OP, p.C1, p.C2, p.C3, p.C4, p.C5, p.R1, p.R2, p.R3, p.R4, p.R5)
+	return err
+}
+
+// GetOperatorProfile returns nil, nil if the profile does not exist.
+func (r *Repository) GetOperatorProfile(ctx context.Context, id string) (*model.OperatorProfile, error) {
+	query := `SELECT ` + operatorProfileColumns + ` FROM public.operator_profiles WHERE id = $1`
+	p, err := scanOperatorProfile(r.Pool.QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+func (r *Repository) ListOperatorProfiles(ctx context.Context) ([]*model.OperatorProfile, error) {
+	query := `SELECT ` + operatorProfileColumns + ` FROM public.operator_profiles ORDER BY id`
+	rows, err := r.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var profiles []*model.OperatorProfile
+	for rows.Next() {
+		p, err := scanOperatorProfile(rows)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, rows.Err()
+}
+
+// UpdateOperatorProfile replaces p in one transaction. The profile row and
+// the subscribers referencing it are locked, and check, if not nil, is
+// called with those subscribers; its error aborts the update. Concurrent
+// subscriber writes that reference the profile wait for the transaction.
+func (r *Repository) UpdateOperatorProfile(ctx context.Context, p *model.OperatorProfile, check func(subs []*model.Subscriber) error) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var id string
+	err = tx.QueryRow(ctx, `SELECT id FROM public.operator_profiles WHERE id = $1 FOR UPDATE`, p.ID).Scan(&id)
+	if err == pgx.ErrNoRows {
+		return ErrOperatorProfileNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if check != nil {
+		query := `
+			SELECT imsi, ki, opc, sqn, amf, algorithm, sqn_profile, COALESCE(operator_profile, ''), created_at
+			FROM public.subscribers
+			WHERE operator_profile = $1
+			FOR UPDATE
+		`
+		rows, err := tx.Query(ctx, query, p.ID)
+		if err != nil {
+			return err
+		}
+		var subs []*model.Subscriber
+		for rows.Next() {
+			var sub model.Subscriber
+			if err := rows.Scan(&sub.IMSI, &sub.Ki, &sub.Opc, &sub.SQN, &sub.AMF, &sub.Algorithm, &sub.SQNProfile, &sub.OperatorProfile, &sub.CreatedAt); err != nil {
+				rows.Close()
+				return err
+			}
+			subs = append(subs, &sub)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if err := check(subs); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		UPDATE public.operator_profiles
+		SET op = $2, c1 = $3, c2 = $4, c3 = $5, c4 = $6, c5 = $7, r1 = $8, r2 = $9, r3 = $10, r4 = $11, r5 = $12
+		WHERE id = $1
+	`
+	if _, err := tx.Exec(ctx, query, p.ID, p.OP, p.C1, p.C2, p.C3, p.C4, p.C5, p.R1, p.R2, p.R3, p.R4, p.R5); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// DeleteOperatorProfile fails while subscribers still reference the profile.
+func (r *Repository) DeleteOperatorProfile(ctx context.Context, id string) error {
+	query := `DELETE FROM public.operator_profiles WHERE id = $1`
+	_, err := r.Pool.Exec(ctx, query, id)
+	return err
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"aka-server/internal/model"
@@ -10,6 +11,10 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrSubscriberNotFound is returned by operations that require an existing
+// subscriber row.
+var ErrSubscriberNotFound = errors.New("subscriber not found")
+
 type Repository struct {
 	Pool *pgxpool.Pool
 }
@@ -32,25 +37,38 @@ func (r *Repository) Close() {
 	r.Pool.Close()
 }
 
+// CreateSubscriber returns ErrOperatorProfileChanged if sub has no OPc and
+// the OP of its operator profile is no longer sub.Operator.OP.
 func (r *Repository) CreateSubscriber(ctx context.Context, sub *model.Subscriber) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if err := lockProfileOP(ctx, tx, sub); err != nil {
+		return err
+	}
+
 	query := `
-		INSERT INTO public.subscribers (imsi, ki, opc, sqn, amf)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO public.subscribers (imsi, ki, opc, sqn, amf, algorithm, sqn_profile, operator_profile)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''))
 	`
-	_, err := r.Pool.Exec(ctx, query, sub.IMSI, sub.Ki, sub.Opc, sub.SQN, sub.AMF)
-	return err
+	if _, err := tx.Exec(ctx, query, sub.IMSI, sub.Ki, sub.Opc, sub.SQN, sub.AMF, sub.Algorithm, sub.SQNProfile, sub.OperatorProfile); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
 func (r *Repository) GetSubscriber(ctx context.Context, imsi string) (*model.Subscriber, error) {
 	query := `
-		SELECT imsi, ki, opc, sqn, amf, created_at
+		SELECT imsi, ki, opc, sqn, amf, algorithm, sqn_profile, COALESCE(operator_profile, ''), created_at
 		FROM public.subscribers
 		WHERE imsi = $1
 	`
 	row := r.Pool.QueryRow(ctx, query, imsi)
 
 	var sub model.Subscriber
-	err := row.Scan(&sub.IMSI, &sub.Ki, &sub.Opc, &sub.SQN, &sub.AMF, &sub.CreatedAt)
+	err := row.Scan(&sub.IMSI, &sub.Ki, &sub.Opc, &sub.SQN, &sub.AMF, &sub.Algorithm, &sub.SQNProfile, &sub.OperatorProfile, &sub.CreatedAt)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
@@ -60,14 +78,26 @@ func (r *Repository) GetSubscriber(ctx context.Context, imsi string) (*model.Sub
 	return &sub, nil
 }
 
+// UpdateSubscriber checks the operator profile like CreateSubscriber.
 func (r *Repository) UpdateSubscriber(ctx context.Context, sub *model.Subscriber) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if err := lockProfileOP(ctx, tx, sub); err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE public.subscribers
-		SET ki = $2, opc = $3, sqn = $4, amf = $5
+		SET ki = $2, opc = $3, sqn = $4, amf = $5, algorithm = $6, sqn_profile = $7, operator_profile = NULLIF($8, '')
 		WHERE imsi = $1
 	`
-	_, err := r.Pool.Exec(ctx, query, sub.IMSI, sub.Ki, sub.Opc, sub.SQN, sub.AMF)
-	return err
+	if _, err := tx.Exec(ctx, query, sub.IMSI, sub.Ki, sub.Opc, sub.SQN, sub.AMF, sub.Algorithm, sub.SQNProfile, sub.OperatorProfile); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
 func (r *Repository) DeleteSubscriber(ctx context.Context, imsi string) error {
@@ -82,6 +112,55 @@ func (r *Repository) UpdateSQN(ctx context.Context, imsi, newSQN string) error {
 	return err
 }
 
+// AdvanceSQN runs fn against the current subscriber record and stores the SQN
+// and auth events it returns, inside a single transaction holding a row lock
+// on the subscriber. Concurrent calls for the same IMSI are serialised, so
+// every caller sees the SQN written by the previous one and no SQN is handed
+// out twice. The subscriber's operator profile is loaded into sub.Operator
+// within the same transaction. If fn returns an error nothing is written.
+func (r *Repository) AdvanceSQN(ctx context.Context, imsi string, fn func(sub *model.Subscriber) (string, []*model.AuthEvent, error)) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		SELECT imsi, ki, opc, sqn, amf, algorithm, sqn_profile, COALESCE(operator_profile, ''), created_at
+		FROM public.subscribers
+		WHERE imsi = $1
+		FOR UPDATE
+	`
+	var sub model.Subscriber
+	err = tx.QueryRow(ctx, query, imsi).Scan(&sub.IMSI, &sub.Ki, &sub.Opc, &sub.SQN, &sub.AMF, &sub.Algorithm, &sub.SQNProfile, &sub.OperatorProfile, &sub.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrSubscriberNotFound
+		}
+		return err
+	}
+
+	if sub.OperatorProfile != "" {
+		query := `SELECT ` + operatorProfileColumns + ` FROM public.operator_profiles WHERE id = $1`
+		if sub.Operator, err = scanOperatorProfile(tx.QueryRow(ctx, query, sub.OperatorProfile)); err != nil {
+			return fmt.Errorf("failed to load operator profile %q: %w", sub.OperatorProfile, err)
+		}
+	}
+
+	newSQN, events, err := fn(&sub)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE public.subscribers SET sqn = $2 WHERE imsi = $1`, imsi, newSQN); err != nil {
+		return err
+	}
+	if err := insertAuthEvents(ctx, tx, events); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
 func (r *Repository) GetSubscriberCount(ctx context.Context) (int64, error) {
 	query := `SELECT COUNT(*) FROM public.subscribers`
 	var count int64
@@ -91,7 +170,7 @@ func (r *Repository) GetSubscriberCount(ctx context.Context) (int64, error) {
 
 func (r *Repository) ListSubscribers(ctx context.Context) ([]*model.Subscriber, error) {
 	query := `
-		SELECT imsi, ki, opc, sqn, amf, created_at
+		SELECT imsi, ki, opc, sqn, amf, algorithm, sqn_profile, COALESCE(operator_profile, ''), created_at
 		FROM public.subscribers
 		ORDER BY imsi ASC
 	`
@@ -104,7 +183,7 @@ func (r *Repository) ListSubscribers(ctx context.Context) ([]*model.Subscriber,
 	var subscribers []*model.Subscriber
 	for rows.Next() {
 		var sub model.Subscriber
-		if err := rows.Scan(&sub.IMSI, &sub.Ki, &sub.Opc, &sub.SQN, &sub.AMF, &sub.CreatedAt); err != nil {
+		if err := rows.Scan(&sub.IMSI, &sub.Ki, &sub.Opc, &sub.SQN, &sub.AMF, &sub.Algorithm, &sub.SQNProfile, &sub.OperatorProfile, &sub.CreatedAt); err != nil {
 			return nil, err
 		}
 		subscribers = append(subscribers, &sub)
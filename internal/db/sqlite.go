@@ -0,0 +1,531 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"aka-server/internal/model"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema mirrors the PostgreSQL schema in docs/user_guide.md.
+// Timestamps are stored as Unix microseconds.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS operator_profiles (
+    id TEXT PRIMARY KEY,
+    op TEXT NOT NULL DEFAULT '',
+    c1 TEXT NOT NULL DEFAULT '',
+    c2 TEXT NOT NULL DEFAULT '',
+    c3 TEXT NOT NULL DEFAULT '',
+    c4 TEXT NOT NULL DEFAULT '',
+    c5 TEXT NOT NULL DEFAULT '',
+    r1 INTEGER,
+    r2 INTEGER,
+    r3 INTEGER,
+    r4 INTEGER,
+    r5 INTEGER,
+    created_at INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS subscribers (
+    imsi TEXT PRIMARY KEY,
+    ki   TEXT NOT NULL,
+    opc  TEXT NOT NULL,
+    sqn  TEXT NOT NULL,
+    amf  TEXT NOT NULL,
+    algorithm TEXT NOT NULL DEFAULT 'milenage',
+    sqn_profile TEXT NOT NULL DEFAULT 'counter',
+    operator_profile TEXT REFERENCES operator_profiles (id),
+    created_at INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS auth_events (
+    id        INTEGER PRIMARY KEY AUTOINCREMENT,
+    imsi      TEXT NOT NULL REFERENCES subscribers (imsi) ON DELETE CASCADE,
+    rand      TEXT NOT NULL,
+    sqn       TEXT NOT NULL,
+    ind       INTEGER NOT NULL,
+    method    TEXT NOT NULL,
+    client    TEXT NOT NULL,
+    node_id   TEXT NOT NULL,
+    resync    BOOLEAN NOT NULL DEFAULT FALSE,
+    issued_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_auth_events_imsi ON auth_events (imsi, issued_at);
+CREATE TABLE IF NOT EXISTS home_network_keys (
+    id          INTEGER PRIMARY KEY CHECK (id BETWEEN 0 AND 255),
+    profile     TEXT NOT NULL CHECK (profile IN ('A', 'B')),
+    private_key TEXT NOT NULL,
+    public_key  TEXT NOT NULL,
+    created_at  INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS imsi_encryption_keys (
+    id          TEXT PRIMARY KEY,
+    private_key TEXT NOT NULL,
+    public_key  TEXT NOT NULL,
+    created_at  INTEGER NOT NULL
+);
+`
+
+// SQLiteStore is a Store on an embedded SQLite database file, for single
+// binary lab deployments. The schema is created on open. All access goes
+// through one connection, so AdvanceSQN transactions are serialised.
+type SQLiteStore struct {
+	DB *sql.DB
+}
+
+// NewSQLiteStore opens or creates the database at path. ":memory:" gives a
+// private in-memory database.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	dsn := "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open SQLite database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("unable to create SQLite schema: %w", err)
+	}
+	return &SQLiteStore{DB: db}, nil
+}
+
+func (s *SQLiteStore) Close() {
+	s.DB.Close()
+}
+
+const sqliteSubscriberColumns = `imsi, ki, opc, sqn, amf, algorithm, sqn_profile, COALESCE(operator_profile, ''), created_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSQLiteSubscriber(row rowScanner) (*model.Subscriber, error) {
+	var sub model.Subscriber
+	var created int64
+	if err := row.Scan(&sub.IMSI, &sub.Ki, &sub.Opc, &sub.SQN, &sub.AMF, &sub.Algorithm, &sub.SQNProfile, &sub.OperatorProfile, &created); err != nil {
+		return nil, err
+	}
+	sub.CreatedAt = time.UnixMicro(created)
+	return &sub, nil
+}
+
+func (s *SQLiteStore) CreateSubscriber(ctx context.Context, sub *model.Subscriber) error {
+	if err := checkSubscriber(sub); err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO subscribers (imsi, ki, opc, sqn, amf, algorithm, sqn_profile, operator_profile, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?)
+	`
+	return s.writeSubscriber(ctx, sub, query, sub.IMSI, sub.Ki, sub.Opc, sub.SQN, sub.AMF, sub.Algorithm, sub.SQNProfile, sub.OperatorProfile, time.Now().UnixMicro())
+}
+
+// writeSubscriber runs a subscriber write in a transaction after checking
+// the operator profile like Repository.CreateSubscriber.
+func (s *SQLiteStore) writeSubscriber(ctx context.Context, sub *model.Subscriber, query string, args ...any) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if sub.OperatorProfile != "" {
+		var op string
+		err := tx.QueryRowContext(ctx, `SELECT op FROM operator_profiles WHERE id = ?`, sub.OperatorProfile).Scan(&op)
+		if err == nil {
+			err = checkProfileOPUnchanged(sub, op)
+		} else if errors.Is(err, sql.ErrNoRows) {
+			// The foreign key rejects the write.
+			err = nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) GetSubscriber(ctx context.Context, imsi string) (*model.Subscriber, error) {
+	query := `SELECT ` + sqliteSubscriberColumns + ` FROM subscribers WHERE imsi = ?`
+	sub, err := scanSQLiteSubscriber(s.DB.QueryRowContext(ctx, query, imsi))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return sub, err
+}
+
+func (s *SQLiteStore) UpdateSubscriber(ctx context.Context, sub *model.Subscriber) error {
+	if err := checkSubscriber(sub); err != nil {
+		return err
+	}
+	query := `
+		UPDATE subscribers
+		SET ki = ?, opc = ?, sqn = ?, amf = ?, algorithm = ?, sqn_profile = ?, operator_profile = NULLIF(?, '')
+		WHERE imsi = ?
+	`
+	return s.writeSubscriber(ctx, sub, query, sub.Ki, sub.Opc, sub.SQN, sub.AMF, sub.Algorithm, sub.SQNProfile, sub.OperatorProfile, sub.IMSI)
+}
+
+func (s *SQLiteStore) DeleteSubscriber(ctx context.Context, imsi string) error {
+	_, err := s.DB.ExecContext(ctx, `DELETE FROM subscribers WHERE imsi = ?`, imsi)
+	return err
+}
+
+func (s *SQLiteStore) UpdateSQN(ctx context.Context, imsi, newSQN string) error {
+	if !sqnPattern.MatchString(newSQN) {
+		return errors.New("sqn must be 6 bytes of hex")
+	}
+	_, err := s.DB.ExecContext(ctx, `UPDATE subscribers SET sqn = ? WHERE imsi = ?`, newSQN, imsi)
+	return err
+}
+
+// AdvanceSQN behaves like Repository.AdvanceSQN. The transaction starts with
+// BEGIN IMMEDIATE, which takes the database write lock up front.
+func (s *SQLiteStore) AdvanceSQN(ctx context.Context, imsi string, fn func(sub *model.Subscriber) (string, []*model.AuthEvent, error)) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + sqliteSubscriberColumns + ` FROM subscribers WHERE imsi = ?`
+	sub, err := scanSQLiteSubscriber(tx.QueryRowContext(ctx, query, imsi))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSubscriberNotFound
+		}
+		return err
+	}
+
+	if sub.OperatorProfile != "" {
+		query := `SELECT ` + operatorProfileColumns + ` FROM operator_profiles WHERE id = ?`
+		if sub.Operator, err = scanSQLiteOperatorProfile(tx.QueryRowContext(ctx, query, sub.OperatorProfile)); err != nil {
+			return fmt.Errorf("failed to load operator profile %q: %w", sub.OperatorProfile, err)
+		}
+	}
+
+	newSQN, events, err := fn(sub)
+	if err != nil {
+		return err
+	}
+	if !sqnPattern.MatchString(newSQN) {
+		return errors.New("sqn must be 6 bytes of hex")
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE subscribers SET sqn = ? WHERE imsi = ?`, newSQN, imsi); err != nil {
+		return err
+	}
+	insert := `
+		INSERT INTO auth_events (imsi, rand, sqn, ind, method, client, node_id, resync, issued_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	now := time.Now().UnixMicro()
+	for _, e := range events {
+		if _, err := tx.ExecContext(ctx, insert, e.IMSI, e.Rand, e.SQN, e.IND, e.Method, e.Client, e.NodeID, e.Resync, now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) GetSubscriberCount(ctx context.Context) (int64, error) {
+	var count int64
+	err := s.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM subscribers`).Scan(&count)
+	return count, err
+}
+
+func (s *SQLiteStore) ListSubscribers(ctx context.Context) ([]*model.Subscriber, error) {
+	query := `SELECT ` + sqliteSubscriberColumns + ` FROM subscribers ORDER BY imsi ASC`
+	rows, err := s.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscribers []*model.Subscriber
+	for rows.Next() {
+		sub, err := scanSQLiteSubscriber(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscribers = append(subscribers, sub)
+	}
+	return subscribers, rows.Err()
+}
+
+// RandIssued reports whether rand was issued to imsi at or after since.
+func (s *SQLiteStore) RandIssued(ctx context.Context, imsi, rand string, since time.Time) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM auth_events
+			WHERE imsi = ? AND rand = lower(?) AND issued_at >= ?
+		)
+	`
+	var ok bool
+	err := s.DB.QueryRowContext(ctx, query, imsi, rand, since.UnixMicro()).Scan(&ok)
+	return ok, err
+}
+
+// ListAuthEvents returns the latest limit auth events of imsi, newest first.
+func (s *SQLiteStore) ListAuthEvents(ctx context.Context, imsi string, limit int) ([]*model.AuthEvent, error) {
+	query := `
+		SELECT id, imsi, rand, sqn, ind, method, client, node_id, resync, issued_at
+		FROM auth_events
+		WHERE imsi = ?
+		ORDER BY issued_at DESC, id DESC
+		LIMIT ?
+	`
+	rows, err := s.DB.QueryContext(ctx, query, imsi, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*model.AuthEvent
+	for rows.Next() {
+		var e model.AuthEvent
+		var issued int64
+		if err := rows.Scan(&e.ID, &e.IMSI, &e.Rand, &e.SQN, &e.IND, &e.Method, &e.Client, &e.NodeID, &e.Resync, &issued); err != nil {
+			return nil, err
+		}
+		e.IssuedAt = time.UnixMicro(issued)
+		events = append(events, &e)
+	}
+	return events, rows.Err()
+}
+
+// DeleteAuthEventsBefore removes auth events issued before before and returns
+// how many were removed.
+func (s *SQLiteStore) DeleteAuthEventsBefore(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.DB.ExecContext(ctx, `DELETE FROM auth_events WHERE issued_at < ?`, before.UnixMicro())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func scanSQLiteOperatorProfile(row rowScanner) (*model.OperatorProfile, error) {
+	var p model.OperatorProfile
+	var created int64
+	err := row.Scan(&p.ID, &p.OP, &p.C1, &p.C2, &p.C3, &p.C4, &p.C5, &p.R1, &p.R2, &p.R3, &p.R4, &p.R5, &created)
+	if err != nil {
+		return nil, err
+	}
+	p.CreatedAt = time.UnixMicro(created)
+	return &p, nil
+}
+
+func (s *SQLiteStore) CreateOperatorProfile(ctx context.Context, p *model.OperatorProfile) error {
+	query := `
+		INSERT INTO operator_profiles (id, op, c1, c2, c3, c4, c5, r1, r2, r3, r4, r5, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err := s.DB.ExecContext(ctx, query, p.ID, p.OP, p.C1, p.C2, p.C3, p.C4, p.C5, p.R1, p.R2, p.R3, p.R4, p.R5, time.Now().UnixMicro())
+	return err
+}
+
+// GetOperatorProfile returns nil, nil if the profile does not exist.
+func (s *SQLiteStore) GetOperatorProfile(ctx context.Context, id string) (*model.OperatorProfile, error) {
+	query := `SELECT ` + operatorProfileColumns + ` FROM operator_profiles WHERE id = ?`
+	p, err := scanSQLiteOperatorProfile(s.DB.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return p, err
+}
+
+func (s *SQLiteStore) ListOperatorProfiles(ctx context.Context) ([]*model.OperatorProfile, error) {
+	query := `SELECT ` + operatorProfileColumns + ` FROM operator_profiles ORDER BY id`
+	rows, err := s.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var profiles []*model.OperatorProfile
+	for rows.Next() {
+		p, err := scanSQLiteOperatorProfile(rows)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, rows.Err()
+}
+
+// UpdateOperatorProfile behaves like Repository.UpdateOperatorProfile. The
+// transaction starts with a write lock, which serializes it with subscriber
+// writes.
+func (s *SQLiteStore) UpdateOperatorProfile(ctx context.Context, p *model.OperatorProfile, check func(subs []*model.Subscriber) error) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE operator_profiles
+		SET op = ?, c1 = ?, c2 = ?, c3 = ?, c4 = ?, c5 = ?, r1 = ?, r2 = ?, r3 = ?, r4 = ?, r5 = ?
+		WHERE id = ?
+	`
+	res, err := tx.ExecContext(ctx, query, p.OP, p.C1, p.C2, p.C3, p.C4, p.C5, p.R1, p.R2, p.R3, p.R4, p.R5, p.ID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrOperatorProfileNotFound
+	}
+
+	if check != nil {
+		rows, err := tx.QueryContext(ctx, `SELECT `+sqliteSubscriberColumns+` FROM subscribers WHERE operator_profile = ?`, p.ID)
+		if err != nil {
+			return err
+		}
+		var subs []*model.Subscriber
+		for rows.Next() {
+			sub, err := scanSQLiteSubscriber(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			subs = append(subs, sub)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if err := check(subs); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteOperatorProfile fails while subscribers still reference the profile.
+func (s *SQLiteStore) DeleteOperatorProfile(ctx context.Context, id string) error {
+	_, err := s.DB.ExecContext(ctx, `DELETE FROM operator_profiles WHERE id = ?`, id)
+	return err
+}
+
+// CreateHomeNetworkKey inserts k and sets k.CreatedAt.
+func (s *SQLiteStore) CreateHomeNetworkKey(ctx context.Context, k *model.HomeNetworkKey) error {
+	now := time.Now().Truncate(time.Microsecond)
+	query := `
+		INSERT INTO home_network_keys (id, profile, private_key, public_key, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	if _, err := s.DB.ExecContext(ctx, query, k.ID, k.Profile, k.PrivateKey, k.PublicKey, now.UnixMicro()); err != nil {
+		return err
+	}
+	k.CreatedAt = now
+	return nil
+}
+
+// GetHomeNetworkKey returns nil, nil if the key does not exist.
+func (s *SQLiteStore) GetHomeNetworkKey(ctx context.Context, id int) (*model.HomeNetworkKey, error) {
+	query := `SELECT id, profile, private_key, public_key, created_at FROM home_network_keys WHERE id = ?`
+	var k model.HomeNetworkKey
+	var created int64
+	err := s.DB.QueryRowContext(ctx, query, id).Scan(&k.ID, &k.Profile, &k.PrivateKey, &k.PublicKey, &created)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	k.CreatedAt = time.UnixMicro(created)
+	return &k, nil
+}
+
+// ListHomeNetworkKeys returns all keys without their private halves.
+func (s *SQLiteStore) ListHomeNetworkKeys(ctx context.Context) ([]*model.HomeNetworkKey, error) {
+	query := `SELECT id, profile, public_key, created_at FROM home_network_keys ORDER BY id`
+	rows, err := s.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*model.HomeNetworkKey
+	for rows.Next() {
+		var k model.HomeNetworkKey
+		var created int64
+		if err := rows.Scan(&k.ID, &k.Profile, &k.PublicKey, &created); err != nil {
+			return nil, err
+		}
+		k.CreatedAt = time.UnixMicro(created)
+		keys = append(keys, &k)
+	}
+	return keys, rows.Err()
+}
+
+func (s *SQLiteStore) DeleteHomeNetworkKey(ctx context.Context, id int) error {
+	_, err := s.DB.ExecContext(ctx, `DELETE FROM home_network_keys WHERE id = ?`, id)
+	return err
+}
+
+// CreateIMSIEncryptionKey inserts k and sets k.CreatedAt.
+func (s *SQLiteStore) CreateIMSIEncryptionKey(ctx context.Context, k *model.IMSIEncryptionKey) error {
+	now := time.Now().Truncate(time.Microsecond)
+	query := `
+		INSERT INTO imsi_encryption_keys (id, private_key, public_key, created_at)
+		VALUES (?, ?, ?, ?)
+	`
+	if _, err := s.DB.ExecContext(ctx, query, k.ID, k.PrivateKey, k.PublicKey, now.UnixMicro()); err != nil {
+		return err
+	}
+	k.CreatedAt = now
+	return nil
+}
+
+// GetIMSIEncryptionKey returns nil, nil if the key does not exist.
+func (s *SQLiteStore) GetIMSIEncryptionKey(ctx context.Context, id string) (*model.IMSIEncryptionKey, error) {
+	query := `SELECT id, private_key, public_key, created_at FROM imsi_encryption_keys WHERE id = ?`
+	var k model.IMSIEncryptionKey
+	var created int64
+	err := s.DB.QueryRowContext(ctx, query, id).Scan(&k.ID, &k.PrivateKey, &k.PublicKey, &created)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	k.CreatedAt = time.UnixMicro(created)
+	return &k, nil
+}
+
+// ListIMSIEncryptionKeys returns all keys, newest first, including their
+// private halves.
+func (s *SQLiteStore) ListIMSIEncryptionKeys(ctx context.Context) ([]*model.IMSIEncryptionKey, error) {
+	query := `SELECT id, private_key, public_key, created_at FROM imsi_encryption_keys ORDER BY created_at DESC, id`
+	rows, err := s.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*model.IMSIEncryptionKey
+	for rows.Next() {
+		var k model.IMSIEncryptionKey
+		var created int64
+		if err := rows.Scan(&k.ID, &k.PrivateKey, &k.PublicKey, &created); err != nil {
+			return nil, err
+		}
+		k.CreatedAt = time.UnixMicro(created)
+		keys = append(keys, &k)
+	}
+	return keys, rows.Err()
+}
+
+func (s *SQLiteStore) DeleteIMSIEncryptionKey(ctx context.Context, id string) error {
+	_, err := s.DB.ExecContext(ctx, `DELETE FROM imsi_encryption_keys WHERE id = ?`, id)
+	return err
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+
+	"aka-server/internal/aka"
+	"aka-server/internal/model"
+)
+
+// Store is the persistence layer used by the API. Repository implements it
+// on PostgreSQL, SQLiteStore on an embedded SQLite file and MemoryStore in
+// memory. Getters return nil, nil for missing rows.
+type Store interface {
+	CreateSubscriber(ctx context.Context, sub *model.Subscriber) error
+	GetSubscriber(ctx context.Context, imsi string) (*model.Subscriber, error)
+	UpdateSubscriber(ctx context.Context, sub *model.Subscriber) error
+	DeleteSubscriber(ctx context.Context, imsi string) error
+	UpdateSQN(ctx context.Context, imsi, newSQN string) error
+	AdvanceSQN(ctx context.Context, imsi string, fn func(sub *model.Subscriber) (string, []*model.AuthEvent, error)) error
+	GetSubscriberCount(ctx context.Context) (int64, error)
+	ListSubscribers(ctx context.Context) ([]*model.Subscriber, error)
+
+	RandIssued(ctx context.Context, imsi, rand string, since time.Time) (bool, error)
+	ListAuthEvents(ctx context.Context, imsi string, limit int) ([]*model.AuthEvent, error)
+	DeleteAuthEventsBefore(ctx context.Context, before time.Time) (int64, error)
+
+	CreateOperatorProfile(ctx context.Context, p *model.OperatorProfile) error
+	GetOperatorProfile(ctx context.Context, id string) (*model.OperatorProfile, error)
+	ListOperatorProfiles(ctx context.Context) ([]*model.OperatorProfile, error)
+	UpdateOperatorProfile(ctx context.Context, p *model.OperatorProfile, check func(subs []*model.Subscriber) error) error
+	DeleteOperatorProfile(ctx context.Context, id string) error
+
+	CreateHomeNetworkKey(ctx context.Context, k *model.HomeNetworkKey) error
+	GetHomeNetworkKey(ctx context.Context, id int) (*model.HomeNetworkKey, error)
+	ListHomeNetworkKeys(ctx context.Context) ([]*model.HomeNetworkKey, error)
+	DeleteHomeNetworkKey(ctx context.Context, id int) error
+
+	CreateIMSIEncryptionKey(ctx context.Context, k *model.IMSIEncryptionKey) error
+	GetIMSIEncryptionKey(ctx context.Context, id string) (*model.IMSIEncryptionKey, error)
+	ListIMSIEncryptionKeys(ctx context.Context) ([]*model.IMSIEncryptionKey, error)
+	DeleteIMSIEncryptionKey(ctx context.Context, id string) error
+
+	Close()
+}
+
+var (
+	_ Store = (*Repository)(nil)
+	_ Store = (*SQLiteStore)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
+
+var (
+	imsiPattern = regexp.MustCompile(`^[0-9]{15}$`)
+	kiPattern   = regexp.MustCompile(`^([0-9a-fA-F]{32}|[0-9a-fA-F]{64})$`)
+	opcPattern  = regexp.MustCompile(`^([0-9a-fA-F]{32}|[0-9a-fA-F]{64})?$`)
+	sqnPattern  = regexp.MustCompile(`^[0-9a-fA-F]{12}$`)
+	amfPattern  = regexp.MustCompile(`^[0-9a-fA-F]{4}$`)
+)
+
+// checkSubscriber applies the CHECK constraints of the PostgreSQL
+// subscribers table for the backends that do not have them.
+func checkSubscriber(sub *model.Subscriber) error {
+	switch {
+	case !imsiPattern.MatchString(sub.IMSI):
+		return errors.New("imsi must be 15 digits")
+	case !kiPattern.MatchString(sub.Ki):
+		return errors.New("ki must be 16 or 32 bytes of hex")
+	case !opcPattern.MatchString(sub.Opc):
+		return errors.New("opc must be empty or 16 or 32 bytes of hex")
+	case !sqnPattern.MatchString(sub.SQN):
+		return errors.New("sqn must be 6 bytes of hex")
+	case !amfPattern.MatchString(sub.AMF):
+		return errors.New("amf must be 2 bytes of hex")
+	}
+	if err := aka.ValidateAlgorithm(sub.Algorithm); err != nil {
+		return err
+	}
+	if err := aka.ValidateKeyLengths(sub.Algorithm, sub.Ki, sub.Opc); err != nil {
+		return err
+	}
+	return aka.ValidateSQNProfile(sub.SQNProfile)
+}
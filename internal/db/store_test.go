@@ -0,0 +1,283 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"aka-server/internal/model"
+)
+
+func TestStoreSubscribers(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		r1 := 64
+		p := &model.OperatorProfile{ID: "test-store-profile", OP: "cdc202d5123e20f62b6d676ac72cb318", R1: &r1}
+		sub := &model.Subscriber{
+			IMSI:            "001019999999997",
+			Ki:              "00112233445566778899aabbccddeeff",
+			SQN:             "000000000020",
+			AMF:             "8000",
+			Algorithm:       "milenage",
+			SQNProfile:      "counter",
+			OperatorProfile: p.ID,
+		}
+		_ = s.DeleteSubscriber(ctx, sub.IMSI)
+		_ = s.DeleteOperatorProfile(ctx, p.ID)
+		t.Cleanup(func() {
+			_ = s.DeleteSubscriber(context.Background(), sub.IMSI)
+			_ = s.DeleteOperatorProfile(context.Background(), p.ID)
+		})
+
+		if err := s.CreateSubscriber(ctx, sub); err == nil {
+			t.Errorf("Expected an error for a missing operator profile")
+		}
+		if err := s.CreateOperatorProfile(ctx, p); err != nil {
+			t.Fatalf("CreateOperatorProfile failed: %v", err)
+		}
+		if err := s.CreateSubscriber(ctx, sub); err != nil {
+			t.Fatalf("CreateSubscriber failed: %v", err)
+		}
+		if err := s.CreateSubscriber(ctx, sub); err == nil {
+			t.Errorf("Expected an error for a duplicate IMSI")
+		}
+		if err := s.DeleteOperatorProfile(ctx, p.ID); err == nil {
+			t.Errorf("Expected an error deleting a referenced operator profile")
+		}
+
+		got, err := s.GetSubscriber(ctx, sub.IMSI)
+		if err != nil || got == nil || got.Ki != sub.Ki || got.OperatorProfile != p.ID || got.CreatedAt.IsZero() {
+			t.Fatalf("GetSubscriber = %+v, %v", got, err)
+		}
+		sub.SQN = "0000000000a0"
+		if err := s.UpdateSubscriber(ctx, sub); err != nil {
+			t.Fatalf("UpdateSubscriber failed: %v", err)
+		}
+		if got, _ := s.GetSubscriber(ctx, sub.IMSI); got.SQN != sub.SQN {
+			t.Errorf("Expected SQN %s after update, got %s", sub.SQN, got.SQN)
+		}
+
+		err = s.AdvanceSQN(ctx, sub.IMSI, func(got *model.Subscriber) (string, []*model.AuthEvent, error) {
+			if got.Operator == nil || got.Operator.OP != p.OP || got.Operator.R1 == nil || *got.Operator.R1 != r1 {
+				t.Errorf("Operator profile not loaded: %+v", got.Operator)
+			}
+			return "0000000000c0", []*model.AuthEvent{{IMSI: sub.IMSI, Rand: "00112233445566778899aabbccddeeff", SQN: "0000000000c0", Method: "eap-aka"}}, nil
+		})
+		if err != nil {
+			t.Fatalf("AdvanceSQN failed: %v", err)
+		}
+		ok, err := s.RandIssued(ctx, sub.IMSI, "00112233445566778899AABBCCDDEEFF", time.Now().Add(-time.Minute))
+		if err != nil || !ok {
+			t.Errorf("RandIssued = %v, %v; want true", ok, err)
+		}
+		if ok, _ := s.RandIssued(ctx, sub.IMSI, "00112233445566778899aabbccddeeff", time.Now().Add(time.Minute)); ok {
+			t.Errorf("RandIssued must not report events before since")
+		}
+
+		// The profile is read inside the transaction, so a change made
+		// between two calls is seen by the next one.
+		p.OP = "00000000000000000000000000000001"
+		if err := s.UpdateOperatorProfile(ctx, p, nil); err != nil {
+			t.Fatalf("UpdateOperatorProfile failed: %v", err)
+		}
+		err = s.AdvanceSQN(ctx, sub.IMSI, func(got *model.Subscriber) (string, []*model.AuthEvent, error) {
+			if got.Operator == nil || got.Operator.OP != p.OP {
+				t.Errorf("Expected the updated operator profile, got %+v", got.Operator)
+			}
+			return "0000000000e0", nil, nil
+		})
+		if err != nil {
+			t.Fatalf("AdvanceSQN failed: %v", err)
+		}
+
+		if err := s.DeleteSubscriber(ctx, sub.IMSI); err != nil {
+			t.Fatalf("DeleteSubscriber failed: %v", err)
+		}
+		if got, err := s.GetSubscriber(ctx, sub.IMSI); got != nil || err != nil {
+			t.Errorf("GetSubscriber after delete = %+v, %v", got, err)
+		}
+		if events, _ := s.ListAuthEvents(ctx, sub.IMSI, 10); len(events) != 0 {
+			t.Errorf("Expected auth events to be deleted with the subscriber, got %d", len(events))
+		}
+	})
+}
+
+func TestStoreDeleteAuthEventsBefore(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		sub := &model.Subscriber{
+			IMSI:       "001019999999996",
+			Ki:         "00112233445566778899aabbccddeeff",
+			Opc:        "000102030405060708090a0b0c0d0e0f",
+			SQN:        "000000000020",
+			AMF:        "8000",
+			Algorithm:  "milenage",
+			SQNProfile: "counter",
+		}
+		_ = s.DeleteSubscriber(ctx, sub.IMSI)
+		t.Cleanup(func() { _ = s.DeleteSubscriber(context.Background(), sub.IMSI) })
+		if err := s.CreateSubscriber(ctx, sub); err != nil {
+			t.Fatalf("CreateSubscriber failed: %v", err)
+		}
+		err := s.AdvanceSQN(ctx, sub.IMSI, func(got *model.Subscriber) (string, []*model.AuthEvent, error) {
+			return "000000000040", []*model.AuthEvent{{IMSI: sub.IMSI, Rand: "00112233445566778899aabbccddeeff", SQN: "000000000040", Method: "eap-aka"}}, nil
+		})
+		if err != nil {
+			t.Fatalf("AdvanceSQN failed: %v", err)
+		}
+
+		if _, err := s.DeleteAuthEventsBefore(ctx, time.Now().Add(-time.Hour)); err != nil {
+			t.Fatalf("DeleteAuthEventsBefore failed: %v", err)
+		}
+		if events, _ := s.ListAuthEvents(ctx, sub.IMSI, 10); len(events) != 1 {
+			t.Errorf("Expected a recent event to be kept, got %d", len(events))
+		}
+		n, err := s.DeleteAuthEventsBefore(ctx, time.Now().Add(time.Minute))
+		if err != nil || n < 1 {
+			t.Errorf("DeleteAuthEventsBefore = %d, %v; want at least 1", n, err)
+		}
+		if events, _ := s.ListAuthEvents(ctx, sub.IMSI, 10); len(events) != 0 {
+			t.Errorf("Expected old events to be deleted, got %d", len(events))
+		}
+	})
+}
+
+func TestStoreOperatorProfiles(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		p := &model.OperatorProfile{ID: "test-store-update"}
+		_ = s.DeleteOperatorProfile(ctx, p.ID)
+		t.Cleanup(func() { _ = s.DeleteOperatorProfile(context.Background(), p.ID) })
+
+		if err := s.UpdateOperatorProfile(ctx, p, nil); !errors.Is(err, ErrOperatorProfileNotFound) {
+			t.Errorf("Expected ErrOperatorProfileNotFound, got %v", err)
+		}
+		if got, err := s.GetOperatorProfile(ctx, p.ID); got != nil || err != nil {
+			t.Errorf("GetOperatorProfile for a missing profile = %+v, %v", got, err)
+		}
+		if err := s.CreateOperatorProfile(ctx, p); err != nil {
+			t.Fatalf("CreateOperatorProfile failed: %v", err)
+		}
+		p.C1 = "00000000000000000000000000000001"
+		if err := s.UpdateOperatorProfile(ctx, p, nil); err != nil {
+			t.Fatalf("UpdateOperatorProfile failed: %v", err)
+		}
+		got, err := s.GetOperatorProfile(ctx, p.ID)
+		if err != nil || got == nil || got.C1 != p.C1 || got.R1 != nil {
+			t.Errorf("GetOperatorProfile = %+v, %v", got, err)
+		}
+	})
+}
+
+func TestStoreOperatorProfileReferences(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		p := &model.OperatorProfile{ID: "test-store-refs", OP: "cdc202d5123e20f62b6d676ac72cb318"}
+		sub := &model.Subscriber{
+			IMSI:            "001019999999995",
+			Ki:              "465b5ce8b199b49faa5f0a2ee238a6bc",
+			SQN:             "000000000020",
+			AMF:             "8000",
+			Algorithm:       "milenage",
+			SQNProfile:      "counter",
+			OperatorProfile: p.ID,
+			Operator:        &model.OperatorProfile{ID: p.ID, OP: p.OP},
+		}
+		other := &model.Subscriber{
+			IMSI:       "001019999999994",
+			Ki:         "465b5ce8b199b49faa5f0a2ee238a6bc",
+			Opc:        "cd63cb71954a9f4e48a5994e37a02baf",
+			SQN:        "000000000020",
+			AMF:        "8000",
+			Algorithm:  "milenage",
+			SQNProfile: "counter",
+		}
+		_ = s.DeleteSubscriber(ctx, sub.IMSI)
+		_ = s.DeleteSubscriber(ctx, other.IMSI)
+		_ = s.DeleteOperatorProfile(ctx, p.ID)
+		t.Cleanup(func() {
+			_ = s.DeleteSubscriber(context.Background(), sub.IMSI)
+			_ = s.DeleteSubscriber(context.Background(), other.IMSI)
+			_ = s.DeleteOperatorProfile(context.Background(), p.ID)
+		})
+		if err := s.CreateOperatorProfile(ctx, p); err != nil {
+			t.Fatalf("CreateOperatorProfile failed: %v", err)
+		}
+		if err := s.CreateSubscriber(ctx, sub); err != nil {
+			t.Fatalf("CreateSubscriber failed: %v", err)
+		}
+		if err := s.CreateSubscriber(ctx, other); err != nil {
+			t.Fatalf("CreateSubscriber failed: %v", err)
+		}
+
+		// check sees only the referencing subscriber, and its error
+		// leaves the profile unchanged.
+		rejected := errors.New("rejected")
+		update := &model.OperatorProfile{ID: p.ID, OP: "00000000000000000000000000000001"}
+		err := s.UpdateOperatorProfile(ctx, update, func(subs []*model.Subscriber) error {
+			if len(subs) != 1 || subs[0].IMSI != sub.IMSI {
+				t.Errorf("check got %d subscribers, want only %s", len(subs), sub.IMSI)
+			}
+			return rejected
+		})
+		if !errors.Is(err, rejected) {
+			t.Errorf("UpdateOperatorProfile = %v, want the check error", err)
+		}
+		if got, _ := s.GetOperatorProfile(ctx, p.ID); got == nil || got.OP != p.OP {
+			t.Errorf("Profile changed despite the check error: %+v", got)
+		}
+
+		if err := s.UpdateOperatorProfile(ctx, update, func([]*model.Subscriber) error { return nil }); err != nil {
+			t.Fatalf("UpdateOperatorProfile failed: %v", err)
+		}
+		// sub was validated against the old OP.
+		if err := s.UpdateSubscriber(ctx, sub); !errors.Is(err, ErrOperatorProfileChanged) {
+			t.Errorf("UpdateSubscriber with a stale profile = %v, want ErrOperatorProfileChanged", err)
+		}
+		sub.Operator.OP = update.OP
+		if err := s.UpdateSubscriber(ctx, sub); err != nil {
+			t.Errorf("UpdateSubscriber failed: %v", err)
+		}
+	})
+}
+
+func TestStoreKeys(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		hnk := &model.HomeNetworkKey{ID: 254, Profile: "A", PrivateKey: "aa", PublicKey: "bb"}
+		_ = s.DeleteHomeNetworkKey(ctx, hnk.ID)
+		t.Cleanup(func() { _ = s.DeleteHomeNetworkKey(context.Background(), hnk.ID) })
+		if err := s.CreateHomeNetworkKey(ctx, hnk); err != nil || hnk.CreatedAt.IsZero() {
+			t.Fatalf("CreateHomeNetworkKey = %v, created_at %v", err, hnk.CreatedAt)
+		}
+		if got, err := s.GetHomeNetworkKey(ctx, hnk.ID); err != nil || got == nil || got.PrivateKey != "aa" {
+			t.Errorf("GetHomeNetworkKey = %+v, %v", got, err)
+		}
+		keys, err := s.ListHomeNetworkKeys(ctx)
+		if err != nil || len(keys) == 0 || keys[len(keys)-1].ID != hnk.ID || keys[len(keys)-1].PrivateKey != "" {
+			t.Errorf("ListHomeNetworkKeys = %+v, %v", keys, err)
+		}
+
+		old := &model.IMSIEncryptionKey{ID: "test-store-old", PrivateKey: "p1", PublicKey: "q1"}
+		cur := &model.IMSIEncryptionKey{ID: "test-store-new", PrivateKey: "p2", PublicKey: "q2"}
+		for _, k := range []*model.IMSIEncryptionKey{old, cur} {
+			_ = s.DeleteIMSIEncryptionKey(ctx, k.ID)
+			t.Cleanup(func() { _ = s.DeleteIMSIEncryptionKey(context.Background(), k.ID) })
+			if err := s.CreateIMSIEncryptionKey(ctx, k); err != nil {
+				t.Fatalf("CreateIMSIEncryptionKey failed: %v", err)
+			}
+			time.Sleep(time.Millisecond)
+		}
+		list, err := s.ListIMSIEncryptionKeys(ctx)
+		if err != nil || len(list) < 2 || list[0].ID != cur.ID || list[0].PrivateKey != "p2" {
+			t.Errorf("ListIMSIEncryptionKeys = %+v, %v; want newest first with private keys", list, err)
+		}
+		if err := s.DeleteIMSIEncryptionKey(ctx, old.ID); err != nil {
+			t.Fatalf("DeleteIMSIEncryptionKey failed: %v", err)
+		}
+		if got, err := s.GetIMSIEncryptionKey(ctx, old.ID); got != nil || err != nil {
+			t.Errorf("GetIMSIEncryptionKey after delete = %+v, %v", got, err)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+// Package eapid issues and resolves the temporary EAP-AKA identities of
+// RFC 4187 4.1.1.7 and RFC 9048: pseudonyms and fast re-authentication
+// identities. Both carry the IMSI encrypted under a server key, so they
+// resolve without a lookup table and reveal nothing to an observer.
+package eapid
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Leading username digits of temporary identities (TS 23.003 19.3.2).
+const (
+	PrefixAKAPseudonym      = '2'
+	PrefixAKAPrimePseudonym = '7'
+	PrefixAKAReauth         = '4'
+	PrefixAKAPrimeReauth    = '8'
+)
+
+// ErrNotTemporary is returned by Codec.Decode for identities that are not
+// shaped like a pseudonym or re-authentication identity.
+var ErrNotTemporary = errors.New("not a temporary identity")
+
+// Codec encrypts IMSIs into temporary identities and back.
+type Codec struct {
+	aead cipher.AEAD
+}
+
+// NewCodec returns a Codec using AES-GCM with key, which must be 16 or 32
+// bytes.
+func NewCodec(key []byte) (*Codec, error) {
+	if len(key) != 16 && len(key) != 32 {
+		return nil, fmt.Errorf("identity key must be 16 or 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Codec{aead: aead}, nil
+}
+
+// NewRandomCodec returns a Codec with a fresh random key. Identities it
+// issues cannot be resolved after a restart.
+func NewRandomCodec() *Codec {
+	key := make([]byte, 32)
+	rand.Read(key) // never fails since Go 1.24
+	c, _ := NewCodec(key)
+	return c
+}
+
+// Encode returns the username of a new temporary identity for imsi. prefix
+// is one of the Prefix constants. Each call gives a different identity.
+func (c *Codec) Encode(imsi string, prefix byte) (string, error) {
+	if !isTemporaryPrefix(prefix) {
+		return "", fmt.Errorf("invalid temporary identity prefix %q", prefix)
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(imsi), []byte{prefix})
+	return string(prefix) + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decode returns the IMSI and prefix of a temporary identity issued by
+// Encode. A realm after "@" is ignored. It returns ErrNotTemporary if id
+// cannot be one of ours, and another error if it looks like one but does
+// not decrypt, e.g. because the key has changed.
+func (c *Codec) Decode(id string) (imsi string, prefix byte, err error) {
+	username, _, _ := strings.Cut(id, "@")
+	if len(username) < 2 || !isTemporaryPrefix(username[0]) {
+		return "", 0, ErrNotTemporary
+	}
+	prefix = username[0]
+	sealed, err := base64.RawURLEncoding.DecodeString(username[1:])
+	if err != nil || len(sealed) < c.aead.NonceSize()+c.aead.Overhead() {
+		return "", 0, ErrNotTemporary
+	}
+	n := c.aead.NonceSize()
+	plain, err := c.aead.Open(nil, sealed[:n], sealed[n:], []byte{prefix})
+	if err != nil {
+		return "", 0, fmt.Errorf("temporary identity does not decrypt")
+	}
+	return string(plain), prefix, nil
+}
+
+// PseudonymPrefix returns the pseudonym prefix for EAP-AKA, or for EAP-AKA'
+// when prime is set.
+func PseudonymPrefix(prime bool) byte {
+	if prime {
+		return PrefixAKAPrimePseudonym
+	}
+	return PrefixAKAPseudonym
+}
+
+// ReauthPrefix returns the fast re-authentication identity prefix for
+// EAP-AKA, or for EAP-AKA' when prime is set.
+func ReauthPrefix(prime bool) byte {
+	if prime {
+		return PrefixAKAPrimeReauth
+	}
+	return PrefixAKAReauth
+}
+
+// IsReauth reports whether prefix denotes a fast re-authentication identity.
+func IsReauth(prefix byte) bool {
+	return prefix == PrefixAKAReauth || prefix == PrefixAKAPrimeReauth
+}
+
+func isTemporaryPrefix(p byte) bool {
+	switch p {
+	case PrefixAKAPseudonym, PrefixAKAPrimePseudonym, PrefixAKAReauth, PrefixAKAPrimeReauth:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package eapid
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCodec(t *testing.T) {
+	c, err := NewCodec([]byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("NewCodec failed: %v", err)
+	}
+	const imsi = "001010123456789"
+
+	a, err := c.Encode(imsi, PrefixAKAPseudonym)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	b, _ := c.Encode(imsi, PrefixAKAPseudonym)
+	if a == b || a[0] != '2' {
+		t.Errorf("Expected distinct pseudonyms starting with 2, got %s and %s", a, b)
+	}
+
+	got, prefix, err := c.Decode(a + "@wlan.mnc001.mcc001.3gppnetwork.org")
+	if err != nil || got != imsi || prefix != PrefixAKAPseudonym {
+		t.Errorf("Decode = %s, %c, %v", got, prefix, err)
+	}
+
+	// The prefix is authenticated: a pseudonym cannot be replayed as a
+	// re-authentication identity.
+	if _, _, err := c.Decode("4" + a[1:]); err == nil || err == ErrNotTemporary {
+		t.Errorf("Expected decryption failure for a changed prefix, got %v", err)
+	}
+	other := NewRandomCodec()
+	if _, _, err := other.Decode(a); err == nil || err == ErrNotTemporary {
+		t.Errorf("Expected decryption failure under another key, got %v", err)
+	}
+	for _, id := range []string{imsi, "0" + imsi + "@wlan.mnc001.mcc001.3gppnetwork.org", "2abc"} {
+		if _, _, err := c.Decode(id); err != ErrNotTemporary {
+			t.Errorf("Decode(%q) = %v, want ErrNotTemporary", id, err)
+		}
+	}
+	if _, err := c.Encode(imsi, '0'); err == nil {
+		t.Error("Expected error for a permanent identity prefix")
+	}
+}
+
+func TestReauthStore(t *testing.T) {
+	now := time.Unix(1000, 0)
+	s := NewReauthStore(time.Hour)
+	s.Now = func() time.Time { return now }
+
+	s.Put(&ReauthContext{ID: "4a", IMSI: "001010123456789", Counter: 1})
+	s.Put(&ReauthContext{ID: "4b", IMSI: "001010123456789", Counter: 1})
+	if got := s.Take("4a"); got == nil || got.Counter != 1 {
+		t.Fatalf("Take returned %v", got)
+	}
+	if s.Take("4a") != nil {
+		t.Error("Expected a re-authentication identity to be used only once")
+	}
+
+	now = now.Add(time.Hour + time.Second)
+	if s.Take("4b") != nil {
+		t.Error("Expected an expired context to be gone")
+	}
+}
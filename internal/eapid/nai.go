@@ -0,0 +1,170 @@
+package eapid
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"aka-server/internal/aka"
+)
+
+// Leading username digits of permanent identities (TS 23.003 19.3.2).
+const (
+	PrefixAKAPermanent      = '0'
+	PrefixAKAPrimePermanent = '6'
+)
+
+// IdentityType classifies an EAP identity by its prefix.
+type IdentityType int
+
+const (
+	Permanent IdentityType = iota
+	Pseudonym
+	Reauth
+)
+
+func (t IdentityType) String() string {
+	switch t {
+	case Permanent:
+		return "permanent"
+	case Pseudonym:
+		return "pseudonym"
+	case Reauth:
+		return "reauth"
+	}
+	return fmt.Sprintf("IdentityType(%d)", int(t))
+}
+
+// Errors wrapped by NAIError.
+var (
+	ErrMalformedNAI  = errors.New("malformed NAI")
+	ErrUnknownPrefix = errors.New("unsupported identity prefix")
+	ErrInvalidIMSI   = errors.New("invalid IMSI in permanent identity")
+	ErrForeignRealm  = errors.New("realm is not a home network")
+	ErrMissingRealm  = errors.New("permanent identity without realm")
+	ErrWrongMethod   = errors.New("identity prefix does not match the EAP method")
+)
+
+// NAIError reports why an EAP identity was rejected. Err is one of the Err
+// variables above.
+type NAIError struct {
+	Identity string
+	Err      error
+}
+
+func (e *NAIError) Error() string {
+	return fmt.Sprintf("%v: %q", e.Err, e.Identity)
+}
+
+func (e *NAIError) Unwrap() error {
+	return e.Err
+}
+
+// NAI is a parsed EAP-AKA or EAP-AKA' identity of the form
+// <prefix><username>[@<realm>] (RFC 4187 4.1.1, TS 23.003 19.3).
+type NAI struct {
+	Username string
+	Realm    string
+	Prefix   byte
+	Type     IdentityType
+	// Prime is set for EAP-AKA' identities.
+	Prime bool
+	// IMSI is set for permanent identities.
+	IMSI string
+	// MCC and MNC are taken from a 3GPP realm such as
+	// wlan.mnc001.mcc001.3gppnetwork.org; the MNC keeps its 3 digits.
+	MCC, MNC string
+}
+
+// ParseNAI parses an EAP identity. It does not decrypt pseudonyms or
+// re-authentication identities; see Codec.Decode. A permanent identity must
+// carry a realm, as in TS 23.003 19.3.2, so that it cannot be mistaken for a
+// bare IMSI.
+func ParseNAI(id string) (*NAI, error) {
+	fail := func(err error) (*NAI, error) {
+		return nil, &NAIError{Identity: id, Err: err}
+	}
+	username, realm, hasRealm := strings.Cut(id, "@")
+	if len(username) < 2 || (hasRealm && (realm == "" || strings.Contains(realm, "@"))) {
+		return fail(ErrMalformedNAI)
+	}
+
+	n := &NAI{Username: username, Realm: realm, Prefix: username[0]}
+	switch n.Prefix {
+	case PrefixAKAPermanent, PrefixAKAPrimePermanent:
+		n.Type = Permanent
+		n.IMSI = username[1:]
+		if !aka.IsDigits(n.IMSI, 6, 15) {
+			return fail(ErrInvalidIMSI)
+		}
+		if !hasRealm {
+			return fail(ErrMissingRealm)
+		}
+	case PrefixAKAPseudonym, PrefixAKAPrimePseudonym:
+		n.Type = Pseudonym
+	case PrefixAKAReauth, PrefixAKAPrimeReauth:
+		n.Type = Reauth
+	default:
+		return fail(ErrUnknownPrefix)
+	}
+	n.Prime = n.Prefix == PrefixAKAPrimePermanent || n.Prefix == PrefixAKAPrimePseudonym || n.Prefix == PrefixAKAPrimeReauth
+
+	if hasRealm {
+		labels := strings.Split(strings.ToLower(realm), ".")
+		for _, l := range labels {
+			if l == "" {
+				return fail(ErrMalformedNAI)
+			}
+			if strings.HasPrefix(l, "mnc") && aka.IsDigits(l[3:], 3, 3) {
+				n.MNC = l[3:]
+			}
+			if strings.HasPrefix(l, "mcc") && aka.IsDigits(l[3:], 3, 3) {
+				n.MCC = l[3:]
+			}
+		}
+		if (n.MCC == "") != (n.MNC == "") {
+			return fail(ErrMalformedNAI)
+		}
+	}
+	return n, nil
+}
+
+// String returns the identity as username[@realm].
+func (n *NAI) String() string {
+	if n.Realm == "" {
+		return n.Username
+	}
+	return n.Username + "@" + n.Realm
+}
+
+// CheckHome verifies that the realm names one of homePLMNs, given as MCC
+// followed by a 2- or 3-digit MNC. An identity without a realm always
+// passes. A realm without MCC and MNC is foreign, and so is every realm when
+// homePLMNs is empty.
+func (n *NAI) CheckHome(homePLMNs []string) error {
+	if n.Realm == "" {
+		return nil
+	}
+	for _, plmn := range homePLMNs {
+		if len(plmn) < 5 {
+			continue
+		}
+		mcc, mnc := plmn[:3], plmn[3:]
+		if len(mnc) == 2 {
+			mnc = "0" + mnc
+		}
+		if n.MCC == mcc && n.MNC == mnc {
+			return nil
+		}
+	}
+	return &NAIError{Identity: n.String(), Err: ErrForeignRealm}
+}
+
+// CheckMethod verifies that the prefix is an EAP-AKA' prefix if prime is
+// set, and an EAP-AKA prefix otherwise (RFC 9048 3.2).
+func (n *NAI) CheckMethod(prime bool) error {
+	if n.Prime != prime {
+		return &NAIError{Identity: n.String(), Err: ErrWrongMethod}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package eapid
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseNAI(t *testing.T) {
+	tests := []struct {
+		id       string
+		typ      IdentityType
+		prime    bool
+		imsi     string
+		mcc, mnc string
+	}{
+		{"0001010123456789@wlan.mnc001.mcc001.3gppnetwork.org", Permanent, false, "001010123456789", "001", "001"},
+		{"6208930000000001@nai.epc.mnc093.mcc208.3gppnetwork.org", Permanent, true, "208930000000001", "208", "093"},
+		{"2AbC-_x@wlan.mnc001.mcc001.3gppnetwork.org", Pseudonym, false, "", "001", "001"},
+		{"7AbC", Pseudonym, true, "", "", ""},
+		{"4AbC@example.com", Reauth, false, "", "", ""},
+		{"8AbC@WLAN.MNC001.MCC001.3GPPNETWORK.ORG", Reauth, true, "", "001", "001"},
+	}
+	for _, tt := range tests {
+		n, err := ParseNAI(tt.id)
+		if err != nil {
+			t.Errorf("ParseNAI(%q) failed: %v", tt.id, err)
+			continue
+		}
+		if n.Type != tt.typ || n.Prime != tt.prime || n.IMSI != tt.imsi || n.MCC != tt.mcc || n.MNC != tt.mnc {
+			t.Errorf("ParseNAI(%q) = %+v", tt.id, n)
+		}
+	}
+}
+
+func TestParseNAIErrors(t *testing.T) {
+	tests := []struct {
+		id   string
+		want error
+	}{
+		{"", ErrMalformedNAI},
+		{"0@realm", ErrMalformedNAI},
+		{"0001010123456789@", ErrMalformedNAI},
+		{"0001010123456789@a@b", ErrMalformedNAI},
+		{"0001010123456789@wlan..org", ErrMalformedNAI},
+		{"0001010123456789@wlan.mnc001.3gppnetwork.org", ErrMalformedNAI},
+		{"1001010123456789@wlan.mnc001.mcc001.3gppnetwork.org", ErrUnknownPrefix},
+		{"user@example.com", ErrUnknownPrefix},
+		{"00010101234x@wlan.mnc001.mcc001.3gppnetwork.org", ErrInvalidIMSI},
+		{"00010101234567890", ErrInvalidIMSI},
+		{"0001010123456789", ErrMissingRealm},
+		{"600101012345678", ErrMissingRealm},
+	}
+	for _, tt := range tests {
+		_, err := ParseNAI(tt.id)
+		var naiErr *NAIError
+		if !errors.As(err, &naiErr) || !errors.Is(err, tt.want) {
+			t.Errorf("ParseNAI(%q) = %v, want %v", tt.id, err, tt.want)
+		}
+	}
+}
+
+func TestCheckHome(t *testing.T) {
+	home := []string{"00101", "208093"}
+	for _, id := range []string{
+		"0001010123456789@wlan.mnc001.mcc001.3gppnetwork.org",
+		"6208930000000001@nai.epc.mnc093.mcc208.3gppnetwork.org",
+		"2AbC",
+	} {
+		n, _ := ParseNAI(id)
+		if err := n.CheckHome(home); err != nil {
+			t.Errorf("CheckHome(%q) failed: %v", id, err)
+		}
+	}
+	for _, id := range []string{
+		"0310410123456789@wlan.mnc410.mcc310.3gppnetwork.org",
+		"0001010123456789@example.com",
+	} {
+		n, _ := ParseNAI(id)
+		if err := n.CheckHome(home); !errors.Is(err, ErrForeignRealm) {
+			t.Errorf("CheckHome(%q) = %v, want ErrForeignRealm", id, err)
+		}
+		if err := n.CheckHome(nil); !errors.Is(err, ErrForeignRealm) {
+			t.Errorf("CheckHome(%q) without home PLMNs = %v, want ErrForeignRealm", id, err)
+		}
+	}
+	if n, _ := ParseNAI("2AbC"); n.CheckHome(nil) != nil {
+		t.Error("An identity without a realm must pass without home PLMNs")
+	}
+}
+
+func TestCheckMethod(t *testing.T) {
+	for _, tt := range []struct {
+		id    string
+		prime bool
+	}{
+		{"0001010123456789@wlan.mnc001.mcc001.3gppnetwork.org", false},
+		{"6001010123456789@wlan.mnc001.mcc001.3gppnetwork.org", true},
+		{"2AbC", false},
+		{"8AbC", true},
+	} {
+		n, _ := ParseNAI(tt.id)
+		if err := n.CheckMethod(tt.prime); err != nil {
+			t.Errorf("CheckMethod(%q, %v) failed: %v", tt.id, tt.prime, err)
+		}
+		if err := n.CheckMethod(!tt.prime); !errors.Is(err, ErrWrongMethod) {
+			t.Errorf("CheckMethod(%q, %v) = %v, want ErrWrongMethod", tt.id, !tt.prime, err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package eapid
+
+import (
+	"sync"
+	"time"
+)
+
+// ReauthContext is the fast re-authentication state bound to one
+// re-authentication identity (RFC 4187 5).
+type ReauthContext struct {
+	ID   string
+	IMSI string
+	// Prime is set for EAP-AKA'.
+	Prime bool
+	// Counter is the last AT_COUNTER value used with these keys.
+	Counter int
+	MK      string
+	KAut    string
+	Expires time.Time
+}
+
+// ReauthStore is an in-memory set of re-authentication contexts that expire
+// after a fixed lifetime.
+type ReauthStore struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	items map[string]*ReauthContext
+	// Now returns the current time. Tests may replace it.
+	Now func() time.Time
+}
+
+// NewReauthStore returns a ReauthStore whose contexts live for ttl.
+func NewReauthStore(ttl time.Duration) *ReauthStore {
+	return &ReauthStore{ttl: ttl, items: make(map[string]*ReauthContext), Now: time.Now}
+}
+
+// Put sets the expiry of c and stores it under its ID.
+func (s *ReauthStore) Put(c *ReauthContext) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.Now()
+	for id, old := range s.items {
+		if now.After(old.Expires) {
+			delete(s.items, id)
+		}
+	}
+	c.Expires = now.Add(s.ttl)
+	s.items[c.ID] = c
+}
+
+// Take removes and returns the context for id. It returns nil if there is
+// none or it has expired. A re-authentication identity is used only once.
+func (s *ReauthStore) Take(id string) *ReauthContext {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.items[id]
+	if !ok {
+		return nil
+	}
+	delete(s.items, id)
+	if s.Now().After(c.Expires) {
+		return nil
+	}
+	return c
+}
@@ -0,0 +1,64 @@
+// Package gba keeps the bootstrapping contexts of a GBA BSF (TS 33.220).
+package gba
+
+import (
+	"sync"
+	"time"
+)
+
+// Context is the key material of one bootstrapping run, identified by its
+// B-TID.
+type Context struct {
+	BTID    string
+	IMSI    string
+	IMPI    string
+	Rand    string
+	Ks      string
+	Expires time.Time
+}
+
+// Store is an in-memory set of bootstrapping contexts that expire after the
+// key lifetime.
+type Store struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	items map[string]*Context
+	// Now returns the current time. Tests may replace it.
+	Now func() time.Time
+}
+
+// NewStore returns a Store whose contexts live for ttl.
+func NewStore(ttl time.Duration) *Store {
+	return &Store{ttl: ttl, items: make(map[string]*Context), Now: time.Now}
+}
+
+// Put sets the expiry of c and stores it under its B-TID.
+func (s *Store) Put(c *Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.Now()
+	for id, old := range s.items {
+		if now.After(old.Expires) {
+			delete(s.items, id)
+		}
+	}
+	c.Expires = now.Add(s.ttl)
+	s.items[c.BTID] = c
+}
+
+// Get returns a copy of the context for btid. It returns nil if there is
+// none or it has expired.
+func (s *Store) Get(btid string) *Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.items[btid]
+	if !ok {
+		return nil
+	}
+	if s.Now().After(c.Expires) {
+		delete(s.items, btid)
+		return nil
+	}
+	cp := *c
+	return &cp
+}
@@ -0,0 +1,30 @@
+package gba
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	now := time.Unix(1000, 0)
+	s := NewStore(time.Hour)
+	s.Now = func() time.Time { return now }
+
+	const btid = "I1U8vpY3qJ0hiuZNrke/NQ==@bsf.mnc001.mcc001.pub.3gppnetwork.org"
+	s.Put(&Context{BTID: btid, IMSI: "001010000000001", Ks: "01"})
+	got := s.Get(btid)
+	if got == nil || got.Ks != "01" || !got.Expires.Equal(now.Add(time.Hour)) {
+		t.Fatalf("Get returned %v", got)
+	}
+	if s.Get("unknown@bsf.mnc001.mcc001.pub.3gppnetwork.org") != nil {
+		t.Error("Expected nil for an unknown B-TID")
+	}
+
+	now = now.Add(time.Hour + time.Second)
+	if s.Get(btid) != nil {
+		t.Error("Expected an expired context to be gone")
+	}
+	if len(s.items) != 0 {
+		t.Errorf("Expected empty store, got %d items", len(s.items))
+	}
+}
@@ -0,0 +1,165 @@
+// Package imsicrypt implements the carrier IMSI encryption used by WLAN
+// handsets for EAP-AKA and EAP-AKA' identity privacy: the permanent identity
+// is encrypted with RSA-OAEP under a public key provisioned by the carrier
+// and sent as
+//
+//	\0<base64 ciphertext>[,<key identifier>][@<realm>]
+//
+// The plaintext is the permanent NAI, e.g.
+// 0001010123456789@wlan.mnc001.mcc001.3gppnetwork.org.
+package imsicrypt
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha1" // MGF1-SHA1
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Prefix marks an encrypted identity.
+const Prefix = '\x00'
+
+// MinKeyBits is the smallest accepted RSA modulus.
+const MinKeyBits = 2048
+
+// ErrDecrypt is returned when a ciphertext does not decrypt under a key.
+var ErrDecrypt = errors.New("identity does not decrypt under this key")
+
+// Identity is an encrypted identity as sent by the peer.
+type Identity struct {
+	Ciphertext []byte
+	// KeyID is empty if the peer did not name its key.
+	KeyID string
+	Realm string
+}
+
+// IsEncrypted reports whether id is an encrypted identity.
+func IsEncrypted(id string) bool {
+	return len(id) > 0 && id[0] == Prefix
+}
+
+// Parse splits an encrypted identity. The ciphertext may be in standard or
+// URL-safe base64, padded or not.
+func Parse(id string) (*Identity, error) {
+	if !IsEncrypted(id) {
+		return nil, errors.New("not an encrypted identity")
+	}
+	body, realm, hasRealm := strings.Cut(id[1:], "@")
+	if hasRealm && realm == "" {
+		return nil, errors.New("empty realm in encrypted identity")
+	}
+	data, keyID, hasKeyID := strings.Cut(body, ",")
+	if hasKeyID && keyID == "" {
+		return nil, errors.New("empty key identifier in encrypted identity")
+	}
+	ct, err := decodeBase64(data)
+	if err != nil || len(ct) == 0 {
+		return nil, errors.New("invalid base64 in encrypted identity")
+	}
+	return &Identity{Ciphertext: ct, KeyID: keyID, Realm: realm}, nil
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(s, "=")
+	if strings.ContainsAny(s, "-_") {
+		return base64.RawURLEncoding.DecodeString(s)
+	}
+	return base64.RawStdEncoding.DecodeString(s)
+}
+
+// Decrypt returns the plaintext identity. RSA-OAEP with SHA-256 is used;
+// MGF1 is tried with SHA-1 first, the default of Java's
+// OAEPWithSHA-256AndMGF1Padding, and then with SHA-256.
+func Decrypt(priv *rsa.PrivateKey, ciphertext []byte) (string, error) {
+	for _, mgf := range []crypto.Hash{crypto.SHA1, crypto.SHA256} {
+		pt, err := priv.Decrypt(nil, ciphertext, &rsa.OAEPOptions{Hash: crypto.SHA256, MGFHash: mgf})
+		if err == nil {
+			return string(pt), nil
+		}
+	}
+	return "", ErrDecrypt
+}
+
+// Encrypt builds an encrypted identity for identity under pub, as a handset
+// would, with RSA-OAEP and SHA-256 for both the label hash and MGF1. keyID
+// and realm are appended when not empty.
+func Encrypt(pub *rsa.PublicKey, identity, keyID, realm string) (string, error) {
+	ct, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, []byte(identity), nil)
+	if err != nil {
+		return "", err
+	}
+	id := string(Prefix) + base64.StdEncoding.EncodeToString(ct)
+	if keyID != "" {
+		id += "," + keyID
+	}
+	if realm != "" {
+		id += "@" + realm
+	}
+	return id, nil
+}
+
+// GenerateKey returns a new RSA key pair of the given size as PEM.
+func GenerateKey(bits int) (privPEM, pubPEM string, err error) {
+	if bits < MinKeyBits {
+		return "", "", fmt.Errorf("RSA keys must have at least %d bits", MinKeyBits)
+	}
+	priv, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return "", "", err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return "", "", err
+	}
+	privPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	pubPEM, err = PublicKeyPEM(priv)
+	return privPEM, pubPEM, err
+}
+
+// ParsePrivateKey parses a PKCS #8 or PKCS #1 PEM RSA private key.
+func ParsePrivateKey(privPEM string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privPEM))
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+	var priv *rsa.PrivateKey
+	switch block.Type {
+	case "PRIVATE KEY":
+		k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if priv, ok = k.(*rsa.PrivateKey); !ok {
+			return nil, errors.New("private key is not an RSA key")
+		}
+	case "RSA PRIVATE KEY":
+		k, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		priv = k
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if priv.N.BitLen() < MinKeyBits {
+		return nil, fmt.Errorf("RSA keys must have at least %d bits", MinKeyBits)
+	}
+	return priv, nil
+}
+
+// PublicKeyPEM returns the PKIX PEM encoding of the public half of priv.
+func PublicKeyPEM(priv *rsa.PrivateKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
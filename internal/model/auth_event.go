@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// AuthEvent records one issued authentication vector.
+type AuthEvent struct {
+	ID       int64     `json:"id" db:"id"`
+	IMSI     string    `json:"imsi" db:"imsi"`
+	Rand     string    `json:"rand" db:"rand"`
+	SQN      string    `json:"sqn" db:"sqn"`
+	IND      int       `json:"ind" db:"ind"`
+	Method   string    `json:"method" db:"method"`
+	Client   string    `json:"client" db:"client"`   // client IP
+	NodeID   string    `json:"node_id" db:"node_id"` // requesting node, see AuthRequest.NodeID
+	Resync   bool      `json:"resync" db:"resync"`   // issued by a resynchronization
+	IssuedAt time.Time `json:"issued_at" db:"issued_at"`
+}
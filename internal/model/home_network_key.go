@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// HomeNetworkKey is a SUCI home network key pair (TS 33.501 6.12). ID is the
+// home network public key identifier (0-255) carried in the SUCI, Profile is
+// "A" (X25519) or "B" (secp256r1). Keys are hex encoded; Profile B public
+// keys are compressed.
+type HomeNetworkKey struct {
+	ID         int       `json:"id" db:"id"`
+	Profile    string    `json:"profile" db:"profile"`
+	PrivateKey string    `json:"private_key,omitempty" db:"private_key"`
+	PublicKey  string    `json:"public_key" db:"public_key"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// IMSIEncryptionKey is an RSA key pair for WLAN IMSI encryption. ID is the
+// key identifier handsets append to encrypted identities, e.g.
+// "CertificateSerialNumber=7". Keys are PEM encoded: PKCS #8 for the private
+// key, PKIX for the public key.
+type IMSIEncryptionKey struct {
+	ID         string    `json:"id" db:"id"`
+	PrivateKey string    `json:"private_key,omitempty" db:"private_key"`
+	PublicKey  string    `json:"public_key" db:"public_key"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// OperatorProfile holds operator-specific algorithm customisation shared by
+// the subscribers that reference it. Empty c values and nil r values mean the
+// TS 35.206 defaults. OP (TOP for TUAK subscribers) is used by subscribers
+// that have no OPc of their own. The API never returns OP.
+type OperatorProfile struct {
+	ID        string    `json:"id" db:"id"`
+	OP        string    `json:"op,omitempty" db:"op"`
+	C1        string    `json:"c1" db:"c1"`
+	C2        string    `json:"c2" db:"c2"`
+	C3        string    `json:"c3" db:"c3"`
+	C4        string    `json:"c4" db:"c4"`
+	C5        string    `json:"c5" db:"c5"`
+	R1        *int      `json:"r1" db:"r1"`
+	R2        *int      `json:"r2" db:"r2"`
+	R3        *int      `json:"r3" db:"r3"`
+	R4        *int      `json:"r4" db:"r4"`
+	R5        *int      `json:"r5" db:"r5"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -3,10 +3,20 @@ package model
 import "time"
 
 type Subscriber struct {
-	IMSI      string    `json:"imsi" db:"imsi"`
-	Ki        string    `json:"ki" db:"ki"`
-	Opc       string    `json:"opc" db:"opc"`
-	SQN       string    `json:"sqn" db:"sqn"`
-	AMF       string    `json:"amf" db:"amf"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	IMSI            string    `json:"imsi" db:"imsi"`
+	Ki              string    `json:"ki" db:"ki"`
+	Opc             string    `json:"opc" db:"opc"` // TOPc when Algorithm is "tuak"; empty when derived from the operator profile's OP
+	SQN             string    `json:"sqn" db:"sqn"`
+	AMF             string    `json:"amf" db:"amf"`
+	Algorithm       string    `json:"algorithm" db:"algorithm"`               // "milenage" (default) or "tuak"
+	SQNProfile      string    `json:"sqn_profile" db:"sqn_profile"`           // "counter" (default) or "time"
+	OperatorProfile string    `json:"operator_profile" db:"operator_profile"` // optional OperatorProfile ID
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+
+	// OP is accepted on create and update in place of Opc. The server stores
+	// the derived OPc; OP itself is never stored or returned.
+	OP string `json:"op,omitempty" db:"-"`
+
+	// Operator is the resolved OperatorProfile, set before vector generation.
+	Operator *OperatorProfile `json:"-" db:"-"`
 }
@@ -0,0 +1,360 @@
+// Package suci implements the SUCI format of TS 23.003 2.2B and the
+// de-concealment (SIDF) of TS 33.501 6.12 and Annex C with the null scheme,
+// ECIES Profile A (X25519) and Profile B (secp256r1).
+package suci
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"aka-server/internal/aka"
+)
+
+// Protection scheme identifiers (TS 33.501 Annex C.1).
+const (
+	SchemeNull     = 0
+	SchemeProfileA = 1
+	SchemeProfileB = 2
+)
+
+// Profile names used for home network keys.
+const (
+	ProfileA = "A"
+	ProfileB = "B"
+)
+
+// ECIES parameters shared by Profile A and B (Annex C.3.4).
+const (
+	encKeyLen = 16
+	icbLen    = 16
+	macKeyLen = 32
+	macLen    = 8
+)
+
+// ErrMACFailure is returned when the MAC tag of the scheme output does not
+// verify, e.g. because the wrong home network key was used.
+var ErrMACFailure = errors.New("SUCI MAC verification failed")
+
+// SUCI is a parsed Subscription Concealed Identifier.
+type SUCI struct {
+	SUPIType         int
+	MCC, MNC         string
+	RoutingIndicator string
+	Scheme           int
+	KeyID            int
+	SchemeOutput     string
+}
+
+// IsSUCI reports whether s looks like a SUCI in NAI string form.
+func IsSUCI(s string) bool {
+	return strings.HasPrefix(s, "suci-")
+}
+
+// Parse parses a SUCI of the form
+// suci-<supi type>-<MCC>-<MNC>-<routing indicator>-<scheme>-<key id>-<scheme output>.
+// Only IMSI-based SUPIs (type 0) are supported.
+func Parse(s string) (*SUCI, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 8 || parts[0] != "suci" {
+		return nil, fmt.Errorf("invalid SUCI format: %q", s)
+	}
+	var u SUCI
+	var err error
+	if u.SUPIType, err = strconv.Atoi(parts[1]); err != nil || u.SUPIType != 0 {
+		return nil, fmt.Errorf("unsupported SUPI type: %s", parts[1])
+	}
+	u.MCC, u.MNC, u.RoutingIndicator = parts[2], parts[3], parts[4]
+	if !aka.IsDigits(u.MCC, 3, 3) || !aka.IsDigits(u.MNC, 2, 3) || !aka.IsDigits(u.RoutingIndicator, 1, 4) {
+		return nil, fmt.Errorf("invalid SUCI MCC/MNC/routing indicator: %q", s)
+	}
+	if u.Scheme, err = strconv.Atoi(parts[5]); err != nil || u.Scheme < 0 || u.Scheme > 15 {
+		return nil, fmt.Errorf("invalid protection scheme: %s", parts[5])
+	}
+	if u.KeyID, err = strconv.Atoi(parts[6]); err != nil || u.KeyID < 0 || u.KeyID > 255 {
+		return nil, fmt.Errorf("invalid home network key id: %s", parts[6])
+	}
+	u.SchemeOutput = parts[7]
+	return &u, nil
+}
+
+// String returns the SUCI in NAI string form.
+func (u *SUCI) String() string {
+	return fmt.Sprintf("suci-%d-%s-%s-%s-%d-%d-%s", u.SUPIType, u.MCC, u.MNC, u.RoutingIndicator, u.Scheme, u.KeyID, u.SchemeOutput)
+}
+
+// Deconceal recovers the IMSI from u. privateKey is the home network private
+// key identified by u.KeyID; it is ignored for the null scheme.
+func Deconceal(u *SUCI, privateKey []byte) (string, error) {
+	var msin string
+	switch u.Scheme {
+	case SchemeNull:
+		if !aka.IsDigits(u.SchemeOutput, 1, 10) {
+			return "", fmt.Errorf("invalid null-scheme MSIN: %q", u.SchemeOutput)
+		}
+		msin = u.SchemeOutput
+	case SchemeProfileA, SchemeProfileB:
+		out, err := hex.DecodeString(u.SchemeOutput)
+		if err != nil {
+			return "", fmt.Errorf("invalid scheme output: %w", err)
+		}
+		plain, err := decrypt(u.Scheme, privateKey, out)
+		if err != nil {
+			return "", err
+		}
+		if msin, err = decodeBCD(plain); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("unsupported protection scheme: %d", u.Scheme)
+	}
+	imsi := u.MCC + u.MNC + msin
+	if len(imsi) > 15 {
+		return "", fmt.Errorf("invalid IMSI length: %d", len(imsi))
+	}
+	return imsi, nil
+}
+
+// Conceal builds a SUCI for imsi, as a UE would. mncLen is the number of MNC
+// digits in imsi. It is used for testing.
+func Conceal(imsi string, mncLen int, routingIndicator string, scheme, keyID int, publicKey []byte) (*SUCI, error) {
+	if !aka.IsDigits(imsi, 6, 15) || (mncLen != 2 && mncLen != 3) {
+		return nil, fmt.Errorf("invalid IMSI: %q", imsi)
+	}
+	u := &SUCI{MCC: imsi[:3], MNC: imsi[3 : 3+mncLen], RoutingIndicator: routingIndicator, Scheme: scheme, KeyID: keyID}
+	msin := imsi[3+mncLen:]
+	switch scheme {
+	case SchemeNull:
+		u.SchemeOutput = msin
+	case SchemeProfileA, SchemeProfileB:
+		out, err := encrypt(scheme, publicKey, encodeBCD(msin))
+		if err != nil {
+			return nil, err
+		}
+		u.SchemeOutput = hex.EncodeToString(out)
+	default:
+		return nil, fmt.Errorf("unsupported protection scheme: %d", scheme)
+	}
+	return u, nil
+}
+
+// GenerateKey creates a home network key pair for profile ("A" or "B"). The
+// public key is returned in the form provisioned to the USIM: 32 bytes for
+// X25519 and 33 bytes (compressed point) for secp256r1.
+func GenerateKey(profile string) (privateKey, publicKey []byte, err error) {
+	curve, err := curveFor(profile)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	pub, err := encodePublicKey(profile, key.PublicKey())
+	return key.Bytes(), pub, err
+}
+
+// PublicKey derives the public key of a home network private key, in the
+// form returned by GenerateKey.
+func PublicKey(profile string, privateKey []byte) ([]byte, error) {
+	curve, err := curveFor(profile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := curve.NewPrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	return encodePublicKey(profile, key.PublicKey())
+}
+
+// ProfileForScheme returns the key profile used by a protection scheme.
+func ProfileForScheme(scheme int) (string, error) {
+	switch scheme {
+	case SchemeProfileA:
+		return ProfileA, nil
+	case SchemeProfileB:
+		return ProfileB, nil
+	}
+	return "", fmt.Errorf("no key profile for protection scheme %d", scheme)
+}
+
+func curveFor(profile string) (ecdh.Curve, error) {
+	switch profile {
+	case ProfileA:
+		return ecdh.X25519(), nil
+	case ProfileB:
+		return ecdh.P256(), nil
+	}
+	return nil, fmt.Errorf("unsupported key profile: %q", profile)
+}
+
+func encodePublicKey(profile string, pub *ecdh.PublicKey) ([]byte, error) {
+	if profile == ProfileA {
+		return pub.Bytes(), nil
+	}
+	x, y := elliptic.Unmarshal(elliptic.P256(), pub.Bytes())
+	if x == nil {
+		return nil, errors.New("invalid P-256 public key")
+	}
+	return elliptic.MarshalCompressed(elliptic.P256(), x, y), nil
+}
+
+// decodePublicKey accepts a raw X25519 key for Profile A and a compressed or
+// uncompressed point for Profile B.
+func decodePublicKey(profile string, b []byte) (*ecdh.PublicKey, error) {
+	curve, err := curveFor(profile)
+	if err != nil {
+		return nil, err
+	}
+	if profile == ProfileB && len(b) == 33 {
+		x, y := elliptic.UnmarshalCompressed(elliptic.P256(), b)
+		if x == nil {
+			return nil, errors.New("invalid compressed P-256 point")
+		}
+		b = elliptic.Marshal(elliptic.P256(), x, y)
+	}
+	return curve.NewPublicKey(b)
+}
+
+func ephemeralKeyLen(scheme int) int {
+	if scheme == SchemeProfileA {
+		return 32
+	}
+	return 33
+}
+
+// keys derives Kenc, ICB and Kmac with the ANSI X9.63 KDF (SHA-256) from the
+// shared secret z and the ephemeral public key as SharedInfo1.
+func keys(z, ephemeral []byte) (encKey, icb, macKey []byte) {
+	var out []byte
+	for counter := uint32(1); len(out) < encKeyLen+icbLen+macKeyLen; counter++ {
+		h := sha256.New()
+		h.Write(z)
+		binary.Write(h, binary.BigEndian, counter)
+		h.Write(ephemeral)
+		out = h.Sum(out)
+	}
+	return out[:encKeyLen], out[encKeyLen : encKeyLen+icbLen], out[encKeyLen+icbLen : encKeyLen+icbLen+macKeyLen]
+}
+
+func decrypt(scheme int, privateKey, out []byte) ([]byte, error) {
+	profile, err := ProfileForScheme(scheme)
+	if err != nil {
+		return nil, err
+	}
+	n := ephemeralKeyLen(scheme)
+	if len(out) <= n+macLen {
+		return nil, fmt.Errorf("scheme output too short: %d bytes", len(out))
+	}
+	ephemeral, cipherText, tag := out[:n], out[n:len(out)-macLen], out[len(out)-macLen:]
+
+	curve, err := curveFor(profile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := curve.NewPrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid home network private key: %w", err)
+	}
+	pub, err := decodePublicKey(profile, ephemeral)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ephemeral public key: %w", err)
+	}
+	z, err := key.ECDH(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	encKey, icb, macKey := keys(z, ephemeral)
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(cipherText)
+	if !hmac.Equal(mac.Sum(nil)[:macLen], tag) {
+		return nil, ErrMACFailure
+	}
+	return ctr(encKey, icb, cipherText)
+}
+
+func encrypt(scheme int, publicKey, plain []byte) ([]byte, error) {
+	profile, err := ProfileForScheme(scheme)
+	if err != nil {
+		return nil, err
+	}
+	hnKey, err := decodePublicKey(profile, publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid home network public key: %w", err)
+	}
+	curve, _ := curveFor(profile)
+	eph, err := curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	z, err := eph.ECDH(hnKey)
+	if err != nil {
+		return nil, err
+	}
+	ephemeral, err := encodePublicKey(profile, eph.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+
+	encKey, icb, macKey := keys(z, ephemeral)
+	cipherText, err := ctr(encKey, icb, plain)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(cipherText)
+	out := append(ephemeral, cipherText...)
+	return append(out, mac.Sum(nil)[:macLen]...), nil
+}
+
+func ctr(key, icb, in []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(in))
+	cipher.NewCTR(block, icb).XORKeyStream(out, in)
+	return out, nil
+}
+
+// decodeBCD decodes MSIN digits packed low nibble first, with 0xF filler.
+func decodeBCD(b []byte) (string, error) {
+	var sb strings.Builder
+	for i, v := range b {
+		for j, d := range []byte{v & 0x0f, v >> 4} {
+			if d == 0x0f && i == len(b)-1 && j == 1 {
+				break
+			}
+			if d > 9 {
+				return "", fmt.Errorf("invalid BCD digit in MSIN: %x", b)
+			}
+			sb.WriteByte('0' + d)
+		}
+	}
+	return sb.String(), nil
+}
+
+func encodeBCD(digits string) []byte {
+	out := make([]byte, 0, (len(digits)+1)/2)
+	for i := 0; i < len(digits); i += 2 {
+		lo := digits[i] - '0'
+		hi := byte(0x0f)
+		if i+1 < len(digits) {
+			hi = digits[i+1] - '0'
+		}
+		out = append(out, hi<<4|lo)
+	}
+	return out
+}
@@ -0,0 +1,122 @@
+package suci
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// TS 33.501 Annex C.4 test data. Both SUCIs conceal the MSIN 001002086
+// (plaintext 00012080f6) under MCC 208, MNC 93.
+const (
+	profileAPrivateKey = "c53c22208b61860b06c62e5406a7b330c2b577aa5558981510d128247d38bd1d"
+	profileAPublicKey  = "5a8d38864820197c3394b92613b20b91633cbd897119273bf8e4a6f4eec0a650"
+	profileASUCI       = "suci-0-208-93-0-1-1-b2e92f836055a255837debf850b528997ce0201cb82adfe4be1f587d07d8457dcb02352410cddd9e730ef3fa87"
+
+	profileBPrivateKey = "f1ab1074477ebcc7f554ea1c5fc368b1616730155e0041ac447d6301975fecda"
+	profileBPublicKey  = "0272da71976234ce833a6907425867b82e074d44ef907dfb4b3e21c1c2256ebcd1"
+	profileBSUCI       = "suci-0-208-93-0-2-2-039aab8376597021e855679a9778ea0b67396e68c66df32c0f41e9acca2da9b9d146a33fc2716ac7dae96aa30a4d"
+
+	testIMSI = "20893001002086"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("bad hex %q: %v", s, err)
+	}
+	return b
+}
+
+func TestDeconcealTestData(t *testing.T) {
+	tests := []struct {
+		name, suci, priv, pub, profile string
+	}{
+		{"Profile A", profileASUCI, profileAPrivateKey, profileAPublicKey, ProfileA},
+		{"Profile B", profileBSUCI, profileBPrivateKey, profileBPublicKey, ProfileB},
+	}
+	for _, tt := range tests {
+		u, err := Parse(tt.suci)
+		if err != nil {
+			t.Fatalf("%s: Parse failed: %v", tt.name, err)
+		}
+		if u.String() != tt.suci {
+			t.Errorf("%s: String() = %s", tt.name, u.String())
+		}
+		imsi, err := Deconceal(u, mustHex(t, tt.priv))
+		if err != nil {
+			t.Fatalf("%s: Deconceal failed: %v", tt.name, err)
+		}
+		if imsi != testIMSI {
+			t.Errorf("%s: got IMSI %s, want %s", tt.name, imsi, testIMSI)
+		}
+
+		pub, err := PublicKey(tt.profile, mustHex(t, tt.priv))
+		if err != nil {
+			t.Fatalf("%s: PublicKey failed: %v", tt.name, err)
+		}
+		if hex.EncodeToString(pub) != tt.pub {
+			t.Errorf("%s: got public key %x", tt.name, pub)
+		}
+	}
+
+	// The Profile A SUCI does not verify under an unrelated key.
+	u, _ := Parse(profileASUCI)
+	if _, err := Deconceal(u, mustHex(t, "0000000000000000000000000000000000000000000000000000000000000009")); err != ErrMACFailure {
+		t.Errorf("Expected ErrMACFailure with the wrong key, got %v", err)
+	}
+}
+
+func TestConcealRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		profile string
+		scheme  int
+	}{{ProfileA, SchemeProfileA}, {ProfileB, SchemeProfileB}} {
+		priv, pub, err := GenerateKey(tc.profile)
+		if err != nil {
+			t.Fatalf("GenerateKey(%s) failed: %v", tc.profile, err)
+		}
+		for _, imsi := range []string{"001010123456789", "00101012345678"} {
+			u, err := Conceal(imsi, 2, "0", tc.scheme, 7, pub)
+			if err != nil {
+				t.Fatalf("Conceal failed: %v", err)
+			}
+			parsed, err := Parse(u.String())
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			got, err := Deconceal(parsed, priv)
+			if err != nil {
+				t.Fatalf("Deconceal failed: %v", err)
+			}
+			if got != imsi {
+				t.Errorf("Profile %s: got %s, want %s", tc.profile, got, imsi)
+			}
+		}
+	}
+}
+
+func TestNullScheme(t *testing.T) {
+	u, err := Parse("suci-0-001-01-0-0-0-0123456789")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	imsi, err := Deconceal(u, nil)
+	if err != nil || imsi != "001010123456789" {
+		t.Errorf("Deconceal = %s, %v", imsi, err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, s := range []string{
+		"001010123456789",
+		"suci-1-001-01-0-0-0-0123456789", // NAI-based SUPI
+		"suci-0-01-01-0-0-0-0123456789",  // short MCC
+		"suci-0-001-01-0-1-256-00",       // key id out of range
+		"suci-0-001-01-0-0-0",            // missing scheme output
+	} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+// Package usim simulates the USIM side of UMTS AKA (TS 33.102 6.3.3) for
+// testing the server end to end.
+package usim
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"aka-server/internal/aka"
+	"aka-server/internal/model"
+)
+
+// ErrMACFailure is returned when the MAC in AUTN does not verify.
+var ErrMACFailure = errors.New("MAC failure")
+
+// SyncFailure is returned when the SQN in AUTN is not acceptable. AUTS is to
+// be sent back to the network for resynchronization.
+type SyncFailure struct {
+	AUTS []byte
+}
+
+func (e *SyncFailure) Error() string {
+	return fmt.Sprintf("synchronisation failure (AUTS %x)", e.AUTS)
+}
+
+// State is the USIM SQN state of TS 33.102 C.2: the highest SEQ accepted for
+// each IND and the highest SQN accepted overall. It can be persisted between
+// runs.
+type State struct {
+	SEQ   []uint64 `json:"seq"`
+	SQNMS uint64   `json:"sqn_ms"`
+}
+
+// USIM holds the subscriber keys and SQN state of one simulated USIM.
+type USIM struct {
+	alg     aka.AlgorithmSet
+	indBits uint
+	delta   uint64
+	State   State
+}
+
+// New returns a USIM for the keys and algorithm of sub, starting from
+// SQN_MS = sub.SQN. indBits and delta must match the server's SQN_IND_BITS
+// and SQN_DELTA.
+func New(sub *model.Subscriber, indBits uint, delta uint64) (*USIM, error) {
+	alg, err := aka.NewAlgorithmSet(sub)
+	if err != nil {
+		return nil, err
+	}
+	if indBits > 16 {
+		return nil, fmt.Errorf("invalid IND length: %d", indBits)
+	}
+	u := &USIM{alg: alg, indBits: indBits, delta: delta}
+	u.State.SEQ = make([]uint64, 1<<indBits)
+	if sub.SQN != "" {
+		if len(sub.SQN) != 12 {
+			return nil, fmt.Errorf("invalid SQN length")
+		}
+		if u.State.SQNMS, err = strconv.ParseUint(sub.SQN, 16, 64); err != nil {
+			return nil, fmt.Errorf("invalid SQN: %w", err)
+		}
+		for i := range u.State.SEQ {
+			u.State.SEQ[i] = u.State.SQNMS >> indBits
+		}
+	}
+	return u, nil
+}
+
+// Restore replaces the SQN state, e.g. with one saved by a previous run.
+func (u *USIM) Restore(s State) error {
+	if len(s.SEQ) != 1<<u.indBits {
+		return fmt.Errorf("state has %d IND entries, want %d", len(s.SEQ), 1<<u.indBits)
+	}
+	u.State = s
+	return nil
+}
+
+// Authenticate verifies AUTN and returns RES, CK and IK. A *SyncFailure is
+// returned when the SQN is not fresh; ErrMACFailure when AUTN is not
+// authentic. The SQN state only changes on success.
+func (u *USIM) Authenticate(rand, autn []byte) (res, ck, ik []byte, err error) {
+	if len(rand) != 16 || len(autn) != 16 {
+		return nil, nil, nil, fmt.Errorf("invalid RAND/AUTN length")
+	}
+	res, ck, ik, ak, err := u.alg.F2345(rand)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	sqnBytes := make([]byte, 6)
+	for i := range sqnBytes {
+		sqnBytes[i] = autn[i] ^ ak[i]
+	}
+	xmac, err := u.alg.F1(rand, sqnBytes, autn[6:8])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if subtle.ConstantTimeCompare(xmac, autn[8:]) != 1 {
+		return nil, nil, nil, ErrMACFailure
+	}
+
+	sqn := binary.BigEndian.Uint64(append([]byte{0, 0}, sqnBytes...))
+	if !u.fresh(sqn) {
+		auts, err := u.auts(rand)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return nil, nil, nil, &SyncFailure{AUTS: auts}
+	}
+
+	seq, ind := sqn>>u.indBits, sqn&(1<<u.indBits-1)
+	u.State.SEQ[ind] = seq
+	if seq > u.State.SQNMS>>u.indBits {
+		u.State.SQNMS = sqn
+	}
+	return res, ck, ik, nil
+}
+
+// fresh applies the checks of TS 33.102 C.2.2: SEQ must be larger than the
+// SEQ last accepted for its IND, and not more than delta ahead of the
+// highest SEQ accepted.
+func (u *USIM) fresh(sqn uint64) bool {
+	seq, ind := sqn>>u.indBits, sqn&(1<<u.indBits-1)
+	if seq <= u.State.SEQ[ind] {
+		return false
+	}
+	seqMS := u.State.SQNMS >> u.indBits
+	return u.delta == 0 || seq <= seqMS || seq-seqMS <= u.delta
+}
+
+// auts computes AUTS = SQN_MS ^ AK* || MAC-S (TS 33.102 6.3.3).
+func (u *USIM) auts(rand []byte) ([]byte, error) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], u.State.SQNMS)
+	sqnMS := b[2:]
+	akStar, err := u.alg.F5Star(rand)
+	if err != nil {
+		return nil, err
+	}
+	macS, err := u.alg.F1Star(rand, sqnMS, []byte{0, 0})
+	if err != nil {
+		return nil, err
+	}
+	auts := make([]byte, 0, 14)
+	for i := range sqnMS {
+		auts = append(auts, sqnMS[i]^akStar[i])
+	}
+	return append(auts, macS...), nil
+}
@@ -0,0 +1,150 @@
+package usim
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"aka-server/internal/aka"
+	"aka-server/internal/model"
+)
+
+func newSubscriber(sqn string) *model.Subscriber {
+	return &model.Subscriber{
+		IMSI: "001010000000001",
+		Ki:   "465b5ce8b199b49faa5f0a2ee238a6bc",
+		Opc:  "cd63cb71954a9f4e48a5994e37a02baf",
+		SQN:  sqn,
+		AMF:  "8000",
+	}
+}
+
+func decode(t *testing.T, vec *aka.AuthVector) (rand, autn []byte) {
+	t.Helper()
+	rand, err := hex.DecodeString(vec.Rand)
+	if err != nil {
+		t.Fatal(err)
+	}
+	autn, err = hex.DecodeString(vec.Autn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rand, autn
+}
+
+func TestAuthenticate(t *testing.T) {
+	sub := newSubscriber("000000000020")
+	u, err := New(sub, 5, 1<<28)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	vec, _, err := aka.GenerateVector(sub)
+	if err != nil {
+		t.Fatalf("GenerateVector failed: %v", err)
+	}
+	rand, autn := decode(t, vec)
+	res, ck, ik, err := u.Authenticate(rand, autn)
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if hex.EncodeToString(res) != vec.Xres || hex.EncodeToString(ck) != vec.Ck || hex.EncodeToString(ik) != vec.Ik {
+		t.Errorf("RES/CK/IK do not match the vector")
+	}
+
+	// Replaying the same AUTN is a sync failure.
+	_, _, _, err = u.Authenticate(rand, autn)
+	var sf *SyncFailure
+	if !errors.As(err, &sf) {
+		t.Fatalf("Expected SyncFailure on replay, got %v", err)
+	}
+
+	// A modified AUTN fails the MAC check.
+	autn[15] ^= 1
+	if _, _, _, err := u.Authenticate(rand, autn); err != ErrMACFailure {
+		t.Errorf("Expected ErrMACFailure, got %v", err)
+	}
+}
+
+func TestResyncRoundTrip(t *testing.T) {
+	// The USIM is at SEQ 100, the network at SEQ 10.
+	u, err := New(newSubscriber("000000000c80"), 5, 1<<28)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	sub := newSubscriber("000000000140")
+
+	vec, _, err := aka.GenerateVector(sub)
+	if err != nil {
+		t.Fatalf("GenerateVector failed: %v", err)
+	}
+	rand, autn := decode(t, vec)
+	_, _, _, err = u.Authenticate(rand, autn)
+	var sf *SyncFailure
+	if !errors.As(err, &sf) {
+		t.Fatalf("Expected SyncFailure, got %v", err)
+	}
+
+	vec, newSQN, err := aka.Resync(sub, vec.Rand, hex.EncodeToString(sf.AUTS))
+	if err != nil {
+		t.Fatalf("Resync failed: %v", err)
+	}
+	if newSQN != "000000000ca0" {
+		t.Errorf("Expected SQN 000000000ca0 after resync, got %s", newSQN)
+	}
+	rand, autn = decode(t, vec)
+	res, _, _, err := u.Authenticate(rand, autn)
+	if err != nil {
+		t.Fatalf("Authenticate after resync failed: %v", err)
+	}
+	if hex.EncodeToString(res) != vec.Xres {
+		t.Error("RES does not match XRES after resync")
+	}
+}
+
+func TestFreshness(t *testing.T) {
+	u, err := New(newSubscriber("000000000000"), 5, 10)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	tests := []struct {
+		sqn  uint64
+		want bool
+	}{
+		{1<<5 | 3, true},   // SEQ 1, IND 3
+		{11<<5 | 0, false}, // more than delta ahead
+	}
+	for _, tt := range tests {
+		if got := u.fresh(tt.sqn); got != tt.want {
+			t.Errorf("fresh(%#x) = %v, want %v", tt.sqn, got, tt.want)
+		}
+	}
+
+	// Accepting SEQ 5 on IND 1 leaves the other IND entries usable for
+	// lower SEQ values.
+	u.State.SEQ[1], u.State.SQNMS = 5, 5<<5|1
+	if u.fresh(5<<5 | 1) {
+		t.Error("Expected SEQ 5 on IND 1 to be stale")
+	}
+	if !u.fresh(3<<5 | 2) {
+		t.Error("Expected SEQ 3 on IND 2 to be fresh")
+	}
+}
+
+func TestRestore(t *testing.T) {
+	u, err := New(newSubscriber("000000000000"), 5, 1<<28)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := u.Restore(State{SEQ: make([]uint64, 4)}); err == nil {
+		t.Error("Expected error for a state with the wrong IND length")
+	}
+	s := State{SEQ: make([]uint64, 32), SQNMS: 0x40}
+	s.SEQ[0] = 2
+	if err := u.Restore(s); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if u.State.SEQ[0] != 2 || u.State.SQNMS != 0x40 {
+		t.Error("State not restored")
+	}
+}